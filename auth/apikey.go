@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"crypto/sha256"
+	"crypto/subtle"
+	"fmt"
+	"strings"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+type apiKeyAuthenticator struct {
+	// key là sha256 của API key để so sánh với độ dài cố định
+	keys map[[sha256.Size]byte]Principal
+}
+
+// NewAPIKeyAuthenticator nhận map API key → principal (ID và TenantID tuỳ chọn).
+func NewAPIKeyAuthenticator(keys map[string]Principal) Authenticator {
+	if len(keys) == 0 {
+		return nil
+	}
+	a := &apiKeyAuthenticator{keys: make(map[[sha256.Size]byte]Principal, len(keys))}
+	for key, p := range keys {
+		p.Method = MethodAPIKey
+		a.keys[sha256.Sum256([]byte(key))] = p
+	}
+	return a
+}
+
+// ParseAPIKeys đọc danh sách dạng "key1:alice,key2:bob:acme,key3:carol:acme:admin",
+// phần thứ ba (tuỳ chọn) là tenant, phần thứ tư (tuỳ chọn) là role của principal.
+func ParseAPIKeys(s string) (map[string]Principal, error) {
+	keys := make(map[string]Principal)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ":")
+		if len(parts) < 2 || len(parts) > 4 || parts[0] == "" || parts[1] == "" {
+			return nil, fmt.Errorf("invalid api key entry %q, want key:principal[:tenant[:role]]", entry)
+		}
+		p := Principal{ID: parts[1]}
+		if len(parts) >= 3 {
+			p.TenantID = parts[2]
+		}
+		if len(parts) == 4 {
+			if parts[3] != RoleAdmin {
+				return nil, fmt.Errorf("invalid api key entry %q: unknown role %q", entry, parts[3])
+			}
+			p.Role = parts[3]
+		}
+		keys[parts[0]] = p
+	}
+	return keys, nil
+}
+
+func (a *apiKeyAuthenticator) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	if looksLikeJWT(credential) {
+		return nil, errSkip
+	}
+
+	sum := sha256.Sum256([]byte(credential))
+	var found *Principal
+	// duyệt hết mọi key để thời gian không phụ thuộc key nào khớp
+	for k, v := range a.keys {
+		if subtle.ConstantTimeCompare(k[:], sum[:]) == 1 {
+			p := v
+			found = &p
+		}
+	}
+	if found == nil {
+		return nil, fmt.Errorf("%w: invalid api key", domain.ErrUnauthenticated)
+	}
+	return found, nil
+}
@@ -0,0 +1,118 @@
+// Package auth xác thực request bằng API key tĩnh hoặc JWT (HS256/RS256).
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+const (
+	MethodAPIKey = "api_key"
+	MethodJWT    = "jwt"
+)
+
+// RoleAdmin là admin của tenant, lấy từ phần thứ tư của API key hoặc claim
+// "role" của JWT.
+const RoleAdmin = "admin"
+
+// Principal là danh tính đã xác thực của request.
+type Principal struct {
+	// ID là tên gắn với API key hoặc claim "sub" của JWT.
+	ID     string
+	Method string
+	// TenantID là workspace của principal, rỗng nếu không thuộc workspace nào.
+	TenantID string
+	// Role rỗng là thành viên thường; RoleAdmin được sửa/xoá mọi item trong tenant.
+	Role string
+	// Claims chỉ có với JWT.
+	Claims map[string]interface{}
+}
+
+// Scope trả về phạm vi item principal được thấy. Principal nil (auth tắt)
+// cho scope rỗng, tức không giới hạn.
+func (p *Principal) Scope() domain.Scope {
+	if p == nil {
+		return domain.Scope{}
+	}
+	return domain.Scope{OwnerID: p.ID, TenantID: p.TenantID, Admin: p.Role == RoleAdmin}
+}
+
+// Authenticator kiểm tra một credential (API key hoặc JWT). Lỗi trả về bọc
+// domain.ErrUnauthenticated; errSkip nghĩa là credential không thuộc loại
+// authenticator này xử lý.
+type Authenticator interface {
+	Authenticate(ctx context.Context, credential string) (*Principal, error)
+}
+
+var errSkip = errors.New("credential not handled")
+
+type chain []Authenticator
+
+// Chain thử lần lượt từng authenticator. Trả về nil khi không có
+// authenticator nào, nghĩa là auth bị tắt.
+func Chain(authenticators ...Authenticator) Authenticator {
+	var c chain
+	for _, a := range authenticators {
+		if a != nil {
+			c = append(c, a)
+		}
+	}
+	if len(c) == 0 {
+		return nil
+	}
+	return c
+}
+
+func (c chain) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	if strings.TrimSpace(credential) == "" {
+		return nil, fmt.Errorf("%w: missing credentials", domain.ErrUnauthenticated)
+	}
+	for _, a := range c {
+		p, err := a.Authenticate(ctx, credential)
+		if errors.Is(err, errSkip) {
+			continue
+		}
+		return p, err
+	}
+	return nil, fmt.Errorf("%w: invalid credentials", domain.ErrUnauthenticated)
+}
+
+type (
+	principalKey struct{}
+	tenantKey    struct{}
+)
+
+func WithPrincipal(ctx context.Context, p *Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// FromContext trả về principal đã xác thực, nil nếu request chưa xác thực
+// (hoặc auth bị tắt).
+func FromContext(ctx context.Context) *Principal {
+	p, _ := ctx.Value(principalKey{}).(*Principal)
+	return p
+}
+
+// looksLikeJWT: JWT compact luôn có đúng 3 phần ngăn bởi dấu chấm.
+func looksLikeJWT(credential string) bool {
+	return strings.Count(credential, ".") == 2
+}
+
+// WithTenant gắn tenant đã được resolve (và kiểm tra) cho request.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// ScopeFrom trả về scope của request: owner là principal, tenant là tenant
+// đã resolve nếu có, không thì tenant của principal.
+func ScopeFrom(ctx context.Context) domain.Scope {
+	scope := FromContext(ctx).Scope()
+	if tenant, ok := ctx.Value(tenantKey{}).(string); ok {
+		scope.TenantID = tenant
+	}
+	return scope
+}
@@ -0,0 +1,158 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key interface{}, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	if kid != "" {
+		token.Header["kid"] = kid
+	}
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return s
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]interface{}{"keys": keys})
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func validClaims(sub string) jwt.MapClaims {
+	return jwt.MapClaims{"sub": sub, "iss": "hub-test", "tenant_id": "acme", "exp": time.Now().Add(time.Hour).Unix()}
+}
+
+func TestAPIKey(t *testing.T) {
+	keys, err := ParseAPIKeys("k1:alice, k2:bob:acme")
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	authn := Chain(NewAPIKeyAuthenticator(keys))
+
+	p, err := authn.Authenticate(context.Background(), "k2")
+	if err != nil || p.ID != "bob" || p.TenantID != "acme" || p.Method != MethodAPIKey {
+		t.Fatalf("got %+v, %v", p, err)
+	}
+	if scope := p.Scope(); scope != (domain.Scope{OwnerID: "bob", TenantID: "acme"}) {
+		t.Fatalf("scope = %+v", scope)
+	}
+	if _, err := authn.Authenticate(context.Background(), "nope"); !errors.Is(err, domain.ErrUnauthenticated) {
+		t.Fatalf("wrong key: got %v, want ErrUnauthenticated", err)
+	}
+	if _, err := ParseAPIKeys("missing-principal"); err == nil {
+		t.Fatal("parse invalid entry: want error")
+	}
+
+	admins, err := ParseAPIKeys("k3:carol:acme:admin")
+	if err != nil {
+		t.Fatalf("parse admin: %v", err)
+	}
+	carol := admins["k3"]
+	if scope := carol.Scope(); scope != (domain.Scope{OwnerID: "carol", TenantID: "acme", Admin: true}) {
+		t.Fatalf("admin scope = %+v", scope)
+	}
+	if _, err := ParseAPIKeys("k4:dave:acme:owner"); err == nil {
+		t.Fatal("parse unknown role: want error")
+	}
+}
+
+func TestJWT(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b64 := base64.RawURLEncoding.EncodeToString
+	jwks := writeJWKS(t,
+		map[string]string{
+			"kty": "RSA", "kid": "rsa-1", "alg": "RS256", "use": "sig",
+			"n": b64(rsaKey.N.Bytes()), "e": b64(big.NewInt(int64(rsaKey.E)).Bytes()),
+		},
+		map[string]string{"kty": "oct", "kid": "hmac-1", "k": b64([]byte("jwks-secret"))},
+	)
+
+	jwtAuth, err := NewJWTAuthenticator(JWTConfig{
+		HS256Secret: []byte("static-secret"),
+		JWKSFile:    jwks,
+		Issuer:      "hub-test",
+	})
+	if err != nil {
+		t.Fatalf("new jwt: %v", err)
+	}
+	authn := Chain(NewAPIKeyAuthenticator(map[string]Principal{"k1": {ID: "alice"}}), jwtAuth)
+
+	valid := map[string]string{
+		"rs256 jwks":  sign(t, jwt.SigningMethodRS256, "rsa-1", rsaKey, validClaims("carol")),
+		"hs256 jwks":  sign(t, jwt.SigningMethodHS256, "hmac-1", []byte("jwks-secret"), validClaims("carol")),
+		"hs256 plain": sign(t, jwt.SigningMethodHS256, "", []byte("static-secret"), validClaims("carol")),
+	}
+	for name, token := range valid {
+		p, err := authn.Authenticate(context.Background(), token)
+		if err != nil || p.ID != "carol" || p.TenantID != "acme" || p.Method != MethodJWT || p.Scope().Admin {
+			t.Fatalf("%s: got %+v, %v", name, p, err)
+		}
+	}
+	admin := validClaims("carol")
+	admin["role"] = RoleAdmin
+	if p, err := authn.Authenticate(context.Background(), sign(t, jwt.SigningMethodHS256, "", []byte("static-secret"), admin)); err != nil || !p.Scope().Admin {
+		t.Fatalf("admin token: got %+v, %v", p, err)
+	}
+
+	expired := validClaims("carol")
+	expired["exp"] = time.Now().Add(-time.Hour).Unix()
+	wrongIssuer := validClaims("carol")
+	wrongIssuer["iss"] = "someone-else"
+	noExp := validClaims("carol")
+	delete(noExp, "exp")
+
+	invalid := map[string]string{
+		"expired":      sign(t, jwt.SigningMethodHS256, "", []byte("static-secret"), expired),
+		"wrong issuer": sign(t, jwt.SigningMethodHS256, "", []byte("static-secret"), wrongIssuer),
+		"no exp":       sign(t, jwt.SigningMethodHS256, "", []byte("static-secret"), noExp),
+		"wrong secret": sign(t, jwt.SigningMethodHS256, "", []byte("guess"), validClaims("carol")),
+		"unknown kid":  sign(t, jwt.SigningMethodRS256, "rsa-2", rsaKey, validClaims("carol")),
+		// HS256 ký bằng kid của key RSA: không được dùng public key làm HMAC secret
+		"alg confusion": sign(t, jwt.SigningMethodHS256, "rsa-1", []byte(b64(rsaKey.N.Bytes())), validClaims("carol")),
+		"alg none":      sign(t, jwt.SigningMethodNone, "", jwt.UnsafeAllowNoneSignatureType, validClaims("carol")),
+	}
+	for name, token := range invalid {
+		if _, err := authn.Authenticate(context.Background(), token); !errors.Is(err, domain.ErrUnauthenticated) {
+			t.Fatalf("%s: got %v, want ErrUnauthenticated", name, err)
+		}
+	}
+}
+
+func TestChainDisabled(t *testing.T) {
+	jwtAuth, err := NewJWTAuthenticator(JWTConfig{})
+	if err != nil {
+		t.Fatalf("new jwt: %v", err)
+	}
+	if authn := Chain(NewAPIKeyAuthenticator(nil), jwtAuth); authn != nil {
+		t.Fatalf("chain without keys = %v, want nil", authn)
+	}
+}
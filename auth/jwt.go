@@ -0,0 +1,173 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+const (
+	// jwtLeeway bù lệch đồng hồ giữa issuer và server khi kiểm tra exp/nbf.
+	jwtLeeway = 30 * time.Second
+	// tenantClaim là claim chứa tenant/workspace của principal.
+	tenantClaim = "tenant_id"
+	// roleClaim là claim chứa role của principal, xem RoleAdmin.
+	roleClaim = "role"
+)
+
+type JWTConfig struct {
+	// HS256Secret dùng cho token HS256 không có header kid.
+	HS256Secret []byte
+	// JWKSFile là file JWKS local chứa key RSA (RS256) và/hoặc oct (HS256),
+	// chọn theo header kid của token.
+	JWKSFile string
+	// Issuer và Audience được kiểm tra nếu khác rỗng.
+	Issuer   string
+	Audience string
+}
+
+type jwtAuthenticator struct {
+	parser   *jwt.Parser
+	secret   []byte
+	rsaKeys  map[string]*rsa.PublicKey
+	hmacKeys map[string][]byte
+}
+
+// NewJWTAuthenticator trả về nil khi cfg không có key nào (JWT bị tắt).
+func NewJWTAuthenticator(cfg JWTConfig) (Authenticator, error) {
+	a := &jwtAuthenticator{
+		secret:   cfg.HS256Secret,
+		rsaKeys:  make(map[string]*rsa.PublicKey),
+		hmacKeys: make(map[string][]byte),
+	}
+	if cfg.JWKSFile != "" {
+		if err := a.loadJWKS(cfg.JWKSFile); err != nil {
+			return nil, err
+		}
+	}
+	if len(a.secret) == 0 && len(a.rsaKeys) == 0 && len(a.hmacKeys) == 0 {
+		return nil, nil
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods([]string{jwt.SigningMethodHS256.Alg(), jwt.SigningMethodRS256.Alg()}),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(jwtLeeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+	a.parser = jwt.NewParser(opts...)
+	return a, nil
+}
+
+func (a *jwtAuthenticator) Authenticate(ctx context.Context, credential string) (*Principal, error) {
+	if !looksLikeJWT(credential) {
+		return nil, errSkip
+	}
+
+	claims := jwt.MapClaims{}
+	if _, err := a.parser.ParseWithClaims(credential, claims, a.key); err != nil {
+		return nil, fmt.Errorf("%w: invalid token: %v", domain.ErrUnauthenticated, err)
+	}
+	sub, err := claims.GetSubject()
+	if err != nil || sub == "" {
+		return nil, fmt.Errorf("%w: token has no subject", domain.ErrUnauthenticated)
+	}
+	tenant, _ := claims[tenantClaim].(string)
+	role, _ := claims[roleClaim].(string)
+	return &Principal{ID: sub, Method: MethodJWT, TenantID: tenant, Role: role, Claims: claims}, nil
+}
+
+// key chọn key theo kid và kiểu thuật toán; key RSA không bao giờ được dùng
+// cho HS256 và ngược lại, tránh lỗi nhầm thuật toán.
+func (a *jwtAuthenticator) key(t *jwt.Token) (interface{}, error) {
+	kid, _ := t.Header["kid"].(string)
+	switch t.Method.(type) {
+	case *jwt.SigningMethodRSA:
+		if key, ok := a.rsaKeys[kid]; ok {
+			return key, nil
+		}
+	case *jwt.SigningMethodHMAC:
+		if key, ok := a.hmacKeys[kid]; ok {
+			return key, nil
+		}
+		if kid == "" && len(a.secret) > 0 {
+			return a.secret, nil
+		}
+	}
+	return nil, fmt.Errorf("no %s key for kid %q", t.Method.Alg(), kid)
+}
+
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	K   string `json:"k"`
+}
+
+func (a *jwtAuthenticator) loadJWKS(path string) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("read jwks: %w", err)
+	}
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return fmt.Errorf("parse jwks %s: %w", path, err)
+	}
+
+	for _, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		switch k.Kty {
+		case "RSA":
+			key, err := k.rsaPublicKey()
+			if err != nil {
+				return fmt.Errorf("jwks key %q: %w", k.Kid, err)
+			}
+			a.rsaKeys[k.Kid] = key
+		case "oct":
+			secret, err := base64.RawURLEncoding.DecodeString(k.K)
+			if err != nil || len(secret) == 0 {
+				return fmt.Errorf("jwks key %q: invalid k", k.Kid)
+			}
+			a.hmacKeys[k.Kid] = secret
+		}
+	}
+	return nil
+}
+
+func (k jwk) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := base64.RawURLEncoding.DecodeString(k.N)
+	if err != nil {
+		return nil, fmt.Errorf("invalid n: %w", err)
+	}
+	e, err := base64.RawURLEncoding.DecodeString(k.E)
+	if err != nil {
+		return nil, fmt.Errorf("invalid e: %w", err)
+	}
+	exp := new(big.Int).SetBytes(e)
+	if len(n) == 0 || !exp.IsInt64() || exp.Int64() < 3 {
+		return nil, errors.New("invalid rsa key")
+	}
+	return &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(exp.Int64())}, nil
+}
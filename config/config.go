@@ -0,0 +1,303 @@
+// Package config gom toàn bộ cấu hình của server vào một struct có kiểu.
+//
+// Giá trị được nạp theo thứ tự, nguồn sau ghi đè nguồn trước:
+//
+//	mặc định < file (--config hoặc CONFIG_FILE, .yaml/.yml/.toml) < biến môi trường < flag
+//
+// Key trong file theo tag yaml của field (ví dụ database.dsn), biến môi
+// trường theo tag env, flag là đường dẫn key nối bằng '-' (ví dụ
+// -database-dsn). Chạy `server -h` để xem đủ danh sách.
+package config
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"os"
+	"time"
+
+	"github.com/JIeeiroSst/hub/auth"
+	"github.com/JIeeiroSst/hub/domain"
+	"github.com/JIeeiroSst/hub/handler"
+	"github.com/JIeeiroSst/hub/repository"
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+type Config struct {
+	Server   ServerConfig   `yaml:"server"`
+	Database DatabaseConfig `yaml:"database"`
+	Redis    RedisConfig    `yaml:"redis"`
+	Hub      HubConfig      `yaml:"hub"`
+	Auth     AuthConfig     `yaml:"auth"`
+	Tenant   TenantConfig   `yaml:"tenant"`
+	CORS     CORSConfig     `yaml:"cors"`
+
+	// RateLimits dạng "route=N/s|m|h[:burst],...", xem handler.ParseRateLimits.
+	RateLimits string `yaml:"rate_limits" env:"RATE_LIMITS"`
+	// ChangeFeed lấy event từ chính DB (trigger/change stream/polling) thay vì outbox.
+	ChangeFeed bool `yaml:"change_feed" env:"CHANGE_FEED"`
+}
+
+type ServerConfig struct {
+	Port              int           `yaml:"port" env:"PORT"`
+	ReadHeaderTimeout time.Duration `yaml:"read_header_timeout" env:"SERVER_READ_HEADER_TIMEOUT"`
+	ReadTimeout       time.Duration `yaml:"read_timeout" env:"SERVER_READ_TIMEOUT"`
+	// SSE tự gỡ write deadline, WebSocket sau khi hijack không bị ảnh hưởng
+	WriteTimeout time.Duration `yaml:"write_timeout" env:"SERVER_WRITE_TIMEOUT"`
+	IdleTimeout  time.Duration `yaml:"idle_timeout" env:"SERVER_IDLE_TIMEOUT"`
+	// ShutdownTimeout là tổng thời gian cho drain request, đóng client và DB;
+	// nên nhỏ hơn terminationGracePeriodSeconds của k8s (mặc định 30s).
+	ShutdownTimeout time.Duration `yaml:"shutdown_timeout" env:"SERVER_SHUTDOWN_TIMEOUT"`
+	// TrustedProxies: IP/CIDR của reverse proxy được tin X-Forwarded-For, dùng
+	// để lấy IP client cho rate limit. Mặc định không tin proxy nào; chạy sau
+	// load balancer thì đặt địa chỉ của nó, không thì mọi client trông như một IP.
+	TrustedProxies []string `yaml:"trusted_proxies" env:"TRUSTED_PROXIES"`
+}
+
+type DatabaseConfig struct {
+	Type repository.DBType `yaml:"type" env:"DB_TYPE"`
+	// DSN bắt buộc với postgres, mysql và sqlite (sqlite: đường dẫn file).
+	DSN             string `yaml:"dsn" env:"DB_DSN" secret:"dsn"`
+	MongoURI        string `yaml:"mongo_uri" env:"MONGO_URI" secret:"dsn"`
+	MongoDB         string `yaml:"mongo_db" env:"MONGO_DB"`
+	MongoCollection string `yaml:"mongo_collection" env:"MONGO_COLL"`
+	// Migrate: "check" không chạy nếu schema cũ; "auto" tự migrate lúc start.
+	Migrate repository.MigrateMode `yaml:"migrate" env:"DB_MIGRATE"`
+	// TenantDatabases: tenant → DSN (MongoDB: tên database); trong env viết
+	// "acme=<dsn>;globex=<dsn>" vì DSN có thể chứa ',' và '='.
+	TenantDatabases map[string]string `yaml:"tenant_databases" env:"TENANT_DATABASES" secret:"dsn"`
+	Pool            PoolConfig        `yaml:"pool"`
+}
+
+// PoolConfig: 0 là giữ mặc định của driver.
+type PoolConfig struct {
+	MaxOpenConns    int           `yaml:"max_open_conns" env:"DB_MAX_OPEN_CONNS"`
+	MaxIdleConns    int           `yaml:"max_idle_conns" env:"DB_MAX_IDLE_CONNS"`
+	ConnMaxLifetime time.Duration `yaml:"conn_max_lifetime" env:"DB_CONN_MAX_LIFETIME"`
+	ConnMaxIdleTime time.Duration `yaml:"conn_max_idle_time" env:"DB_CONN_MAX_IDLE_TIME"`
+}
+
+// RedisConfig: Addr khác rỗng thì bật backplane để event tới client ở mọi replica.
+type RedisConfig struct {
+	Addr     string `yaml:"addr" env:"REDIS_ADDR"`
+	Password string `yaml:"password" env:"REDIS_PASSWORD" secret:"true"`
+	Channel  string `yaml:"channel" env:"REDIS_CHANNEL"`
+}
+
+type HubConfig struct {
+	// MaxConnsPerIdentity: số kết nối WebSocket/SSE đồng thời tối đa của
+	// một API key/principal/IP, 0 là không giới hạn.
+	MaxConnsPerIdentity int                   `yaml:"max_conns_per_identity" env:"WS_MAX_CONNS_PER_IDENTITY"`
+	SlowConsumer        ws.SlowConsumerPolicy `yaml:"slow_consumer" env:"WS_SLOW_CONSUMER"`
+	ReplayBuffer        int                   `yaml:"replay_buffer" env:"WS_REPLAY_BUFFER"`
+	// MaxSubscriptions: số subscription tối đa của một client, 0 là không giới hạn.
+	MaxSubscriptions int `yaml:"max_subscriptions" env:"WS_MAX_SUBSCRIPTIONS"`
+	// BroadcastOverflow: block (chờ tối đa BroadcastTimeout) | drop | spill
+	// (ghi tạm vào Redis list SpillKey, cần redis.addr).
+	BroadcastOverflow ws.OverflowPolicy `yaml:"broadcast_overflow" env:"WS_BROADCAST_OVERFLOW"`
+	BroadcastTimeout  time.Duration     `yaml:"broadcast_timeout" env:"WS_BROADCAST_TIMEOUT"`
+	// SpillKey phải khác nhau giữa các replica, mặc định hub:spill:<hostname>.
+	SpillKey string `yaml:"spill_key" env:"WS_SPILL_KEY"`
+}
+
+// AuthConfig: không đặt gì thì auth tắt.
+type AuthConfig struct {
+	// APIKeys dạng "key1:alice,key2:bob:acme,key3:carol:acme:admin"
+	// (key:principal[:tenant[:role]]); admin được sửa/xoá item của cả tenant.
+	APIKeys     string `yaml:"api_keys" env:"AUTH_API_KEYS" secret:"true"`
+	JWTSecret   string `yaml:"jwt_secret" env:"AUTH_JWT_SECRET" secret:"true"`
+	JWKSFile    string `yaml:"jwks_file" env:"AUTH_JWKS_FILE"`
+	JWTIssuer   string `yaml:"jwt_issuer" env:"AUTH_JWT_ISSUER"`
+	JWTAudience string `yaml:"jwt_audience" env:"AUTH_JWT_AUDIENCE"`
+}
+
+// TenantConfig: tenant lấy từ credential; header/subdomain chỉ để chọn
+// tenant khi auth tắt.
+type TenantConfig struct {
+	Header     string `yaml:"header" env:"TENANT_HEADER"`
+	BaseDomain string `yaml:"base_domain" env:"TENANT_BASE_DOMAIN"`
+	Required   bool   `yaml:"required" env:"TENANT_REQUIRED"`
+}
+
+// CORSConfig xem handler.CORSConfig; list trong env ngăn cách bằng ','.
+type CORSConfig struct {
+	AllowedOrigins   []string      `yaml:"allowed_origins" env:"CORS_ALLOWED_ORIGINS"`
+	AllowedMethods   []string      `yaml:"allowed_methods" env:"CORS_ALLOWED_METHODS"`
+	AllowedHeaders   []string      `yaml:"allowed_headers" env:"CORS_ALLOWED_HEADERS"`
+	ExposedHeaders   []string      `yaml:"exposed_headers" env:"CORS_EXPOSED_HEADERS"`
+	AllowCredentials bool          `yaml:"allow_credentials" env:"CORS_ALLOW_CREDENTIALS"`
+	MaxAge           time.Duration `yaml:"max_age" env:"CORS_MAX_AGE"`
+}
+
+// DefaultRateLimits: tạo item fan-out tới mọi client nên bị giới hạn chặt hơn.
+const DefaultRateLimits = "POST /api/v1/items=5/s:10,GET /ws=1/s:5,*=50/s:100"
+
+// Default trả về cấu hình mặc định. Không có DSN mặc định: credential của
+// database phải được cấu hình rõ ràng.
+func Default() *Config {
+	hostname, _ := os.Hostname()
+	return &Config{
+		Server: ServerConfig{
+			Port:              8080,
+			ReadHeaderTimeout: 10 * time.Second,
+			ReadTimeout:       30 * time.Second,
+			WriteTimeout:      30 * time.Second,
+			IdleTimeout:       2 * time.Minute,
+			ShutdownTimeout:   25 * time.Second,
+		},
+		Database: DatabaseConfig{
+			Type:            repository.DBTypePostgres,
+			MongoDB:         "items_db",
+			MongoCollection: "items",
+			Migrate:         repository.MigrateCheck,
+		},
+		Redis: RedisConfig{Channel: "hub:events"},
+		Hub: HubConfig{
+			MaxConnsPerIdentity: 20,
+			SlowConsumer:        ws.SlowConsumerDisconnect,
+			ReplayBuffer:        256,
+			MaxSubscriptions:    100,
+			BroadcastOverflow:   ws.OverflowBlock,
+			BroadcastTimeout:    time.Second,
+			SpillKey:            "hub:spill:" + hostname,
+		},
+		// mặc định chỉ mở cho localhost (demo.html chạy qua http://localhost),
+		// production đặt allowed_origins là domain thật
+		CORS: CORSConfig{
+			AllowedOrigins: []string{"http://localhost:*", "http://127.0.0.1:*"},
+			AllowedMethods: []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+			AllowedHeaders: []string{"Content-Type", "Authorization", "X-API-Key", "X-Tenant-ID", "Last-Event-ID"},
+			ExposedHeaders: []string{"X-Event-Seq", "Retry-After"},
+			MaxAge:         10 * time.Minute,
+		},
+		RateLimits: DefaultRateLimits,
+	}
+}
+
+// Validate kiểm tra toàn bộ cấu hình và trả về mọi lỗi cùng lúc.
+func (c *Config) Validate() error {
+	var errs []error
+	check := func(ok bool, format string, args ...any) {
+		if !ok {
+			errs = append(errs, fmt.Errorf(format, args...))
+		}
+	}
+
+	s := c.Server
+	check(s.Port > 0 && s.Port <= 65535, "server.port: %d out of range", s.Port)
+	check(s.ReadHeaderTimeout >= 0 && s.ReadTimeout >= 0 && s.WriteTimeout >= 0 && s.IdleTimeout >= 0,
+		"server: timeouts must not be negative")
+	check(s.ShutdownTimeout > 0, "server.shutdown_timeout: must be positive")
+	for _, proxy := range s.TrustedProxies {
+		_, _, cidrErr := net.ParseCIDR(proxy)
+		check(cidrErr == nil || net.ParseIP(proxy) != nil, "server.trusted_proxies: invalid IP or CIDR %q", proxy)
+	}
+
+	db := c.Database
+	switch db.Type {
+	case repository.DBTypePostgres, repository.DBTypeMySQL, repository.DBTypeSQLite:
+		check(db.DSN != "", "database.dsn: required for %s", db.Type)
+	case repository.DBTypeMongoDB:
+		check(db.MongoURI != "", "database.mongo_uri: required for mongodb")
+		check(db.MongoDB != "", "database.mongo_db: required for mongodb")
+		check(db.MongoCollection != "", "database.mongo_collection: required for mongodb")
+	case repository.DBTypeMemory:
+	default:
+		errs = append(errs, fmt.Errorf("database.type: unsupported %q, want postgres, mysql, sqlite, mongodb or memory", db.Type))
+	}
+	check(db.Migrate == repository.MigrateCheck || db.Migrate == repository.MigrateAuto,
+		"database.migrate: invalid %q, want check or auto", db.Migrate)
+	for tenantID, dsn := range db.TenantDatabases {
+		check(domain.ValidTenantID(tenantID), "database.tenant_databases: invalid tenant id %q", tenantID)
+		check(dsn != "", "database.tenant_databases: empty dsn for tenant %q", tenantID)
+	}
+	p := db.Pool
+	check(p.MaxOpenConns >= 0 && p.MaxIdleConns >= 0 && p.ConnMaxLifetime >= 0 && p.ConnMaxIdleTime >= 0,
+		"database.pool: values must not be negative")
+
+	h := c.Hub
+	check(h.MaxConnsPerIdentity >= 0, "hub.max_conns_per_identity: must not be negative")
+	check(h.ReplayBuffer >= 0, "hub.replay_buffer: must not be negative")
+	check(h.MaxSubscriptions >= 0, "hub.max_subscriptions: must not be negative")
+	check(h.BroadcastTimeout >= 0, "hub.broadcast_timeout: must not be negative")
+	if _, err := ws.ParseSlowConsumerPolicy(string(h.SlowConsumer)); err != nil {
+		errs = append(errs, fmt.Errorf("hub.slow_consumer: %w", err))
+	}
+	if _, err := ws.ParseOverflowPolicy(string(h.BroadcastOverflow)); err != nil {
+		errs = append(errs, fmt.Errorf("hub.broadcast_overflow: %w", err))
+	}
+	if h.BroadcastOverflow == ws.OverflowSpill {
+		check(c.Redis.Addr != "", "hub.broadcast_overflow: spill requires redis.addr")
+		check(h.SpillKey != "", "hub.spill_key: required for spill")
+	}
+	check(c.Redis.Addr == "" || c.Redis.Channel != "", "redis.channel: required with redis.addr")
+
+	if _, err := auth.ParseAPIKeys(c.Auth.APIKeys); err != nil {
+		errs = append(errs, fmt.Errorf("auth.api_keys: %w", err))
+	}
+	if err := c.CORS.Handler().Validate(); err != nil {
+		errs = append(errs, err)
+	}
+	if _, err := handler.ParseRateLimits(c.RateLimits); err != nil {
+		errs = append(errs, fmt.Errorf("rate_limits: %w", err))
+	}
+	return errors.Join(errs...)
+}
+
+// Repository chuyển sang cấu hình của package repository.
+func (d DatabaseConfig) Repository() repository.DBConfig {
+	return repository.DBConfig{
+		Type:          d.Type,
+		DSN:           d.DSN,
+		MongoURI:      d.MongoURI,
+		MongoDBName:   d.MongoDB,
+		MongoCollName: d.MongoCollection,
+		Migrate:       d.Migrate,
+		TenantDSNs:    d.TenantDatabases,
+		Pool: repository.PoolConfig{
+			MaxOpenConns:    d.Pool.MaxOpenConns,
+			MaxIdleConns:    d.Pool.MaxIdleConns,
+			ConnMaxLifetime: d.Pool.ConnMaxLifetime,
+			ConnMaxIdleTime: d.Pool.ConnMaxIdleTime,
+		},
+	}
+}
+
+// HubOptions trả về option của hub, trừ backplane và spill (cần client Redis).
+func (h HubConfig) HubOptions() []ws.Option {
+	return []ws.Option{
+		ws.WithMaxConnsPerIdentity(h.MaxConnsPerIdentity),
+		ws.WithSlowConsumerPolicy(h.SlowConsumer),
+		ws.WithReplayBuffer(h.ReplayBuffer),
+		ws.WithMaxSubscriptions(h.MaxSubscriptions),
+		ws.WithOverflowPolicy(h.BroadcastOverflow, h.BroadcastTimeout),
+	}
+}
+
+func (a AuthConfig) JWT() auth.JWTConfig {
+	return auth.JWTConfig{
+		HS256Secret: []byte(a.JWTSecret),
+		JWKSFile:    a.JWKSFile,
+		Issuer:      a.JWTIssuer,
+		Audience:    a.JWTAudience,
+	}
+}
+
+func (t TenantConfig) Resolver() *handler.TenantResolver {
+	return &handler.TenantResolver{
+		Header:     t.Header,
+		BaseDomain: t.BaseDomain,
+		Required:   t.Required,
+	}
+}
+
+func (c CORSConfig) Handler() *handler.CORSConfig {
+	return &handler.CORSConfig{
+		AllowedOrigins:   c.AllowedOrigins,
+		AllowedMethods:   c.AllowedMethods,
+		AllowedHeaders:   c.AllowedHeaders,
+		ExposedHeaders:   c.ExposedHeaders,
+		AllowCredentials: c.AllowCredentials,
+		MaxAge:           c.MaxAge,
+	}
+}
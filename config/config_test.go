@@ -0,0 +1,186 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/JIeeiroSst/hub/repository"
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+func envFrom(m map[string]string) func(string) (string, bool) {
+	return func(k string) (string, bool) {
+		v, ok := m[k]
+		return v, ok
+	}
+}
+
+func writeFile(t *testing.T, name, content string) string {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeFile(t, "hub.yaml", `
+server:
+  port: 9000
+  shutdown_timeout: 10s
+database:
+  type: sqlite
+  dsn: /data/file.db
+  pool:
+    max_open_conns: 5
+hub:
+  replay_buffer: 64
+  max_conns_per_identity: 7
+cors:
+  allowed_origins: [https://app.example.com]
+`)
+	env := envFrom(map[string]string{
+		"CONFIG_FILE":      path,
+		"DB_DSN":           "/data/env.db",
+		"WS_REPLAY_BUFFER": "128",
+		"TENANT_HEADER":    "X-Tenant-ID",
+		"REDIS_PASSWORD":   "", // rỗng coi như không đặt
+	})
+
+	cfg, args, err := load([]string{"-hub-replay-buffer=512", "-tenant-required", "migrate", "up"}, env)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// flag > env > file > mặc định
+	if cfg.Hub.ReplayBuffer != 512 {
+		t.Errorf("hub.replay_buffer = %d, want 512 from flag", cfg.Hub.ReplayBuffer)
+	}
+	if cfg.Database.DSN != "/data/env.db" {
+		t.Errorf("database.dsn = %q, want env value", cfg.Database.DSN)
+	}
+	if cfg.Server.Port != 9000 || cfg.Server.ShutdownTimeout != 10*time.Second || cfg.Hub.MaxConnsPerIdentity != 7 {
+		t.Errorf("file values not applied: %+v %+v", cfg.Server, cfg.Hub)
+	}
+	if cfg.Database.Pool.MaxOpenConns != 5 || cfg.Database.Type != repository.DBTypeSQLite {
+		t.Errorf("database = %+v", cfg.Database)
+	}
+	if cfg.Server.ReadTimeout != 30*time.Second || cfg.Hub.SlowConsumer != ws.SlowConsumerDisconnect {
+		t.Errorf("defaults lost: %+v %+v", cfg.Server, cfg.Hub)
+	}
+	if len(cfg.CORS.AllowedOrigins) != 1 || cfg.CORS.AllowedOrigins[0] != "https://app.example.com" {
+		t.Errorf("cors.allowed_origins = %v", cfg.CORS.AllowedOrigins)
+	}
+	if !cfg.Tenant.Required || cfg.Tenant.Header != "X-Tenant-ID" {
+		t.Errorf("tenant = %+v", cfg.Tenant)
+	}
+	if strings.Join(args, " ") != "migrate up" {
+		t.Errorf("args = %v, want [migrate up]", args)
+	}
+}
+
+func TestLoadTOML(t *testing.T) {
+	path := writeFile(t, "hub.toml", `
+rate_limits = "*=10/s"
+
+[database]
+type = "mongodb"
+mongo_uri = "mongodb://mongo:27017"
+
+[database.tenant_databases]
+acme = "acme_db"
+
+[hub]
+broadcast_timeout = "250ms"
+`)
+	cfg, _, err := load([]string{"-config", path}, envFrom(nil))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Database.Type != repository.DBTypeMongoDB || cfg.Database.Repository().TenantDSNs["acme"] != "acme_db" {
+		t.Errorf("database = %+v", cfg.Database)
+	}
+	if cfg.Hub.BroadcastTimeout != 250*time.Millisecond || cfg.RateLimits != "*=10/s" {
+		t.Errorf("hub.broadcast_timeout = %s, rate_limits = %q", cfg.Hub.BroadcastTimeout, cfg.RateLimits)
+	}
+}
+
+func TestLoadRejectsUnknownFileKeys(t *testing.T) {
+	path := writeFile(t, "hub.yaml", "database:\n  type: memory\n  dns: typo\nhub:\n  shard: 2\n")
+	_, _, err := load(nil, envFrom(map[string]string{"CONFIG_FILE": path}))
+	if err == nil || !strings.Contains(err.Error(), "database.dns, hub.shard") {
+		t.Fatalf("got %v, want unknown keys error", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	tests := []struct {
+		name string
+		env  map[string]string
+		want []string
+	}{
+		{"sql without dsn", map[string]string{"DB_TYPE": "mysql"}, []string{"database.dsn: required for mysql"}},
+		{"mongodb without uri", map[string]string{"DB_TYPE": "mongodb", "MONGO_DB": ""}, []string{"database.mongo_uri"}},
+		{"unknown db type", map[string]string{"DB_TYPE": "oracle"}, []string{"database.type"}},
+		{"spill without redis", map[string]string{"DB_TYPE": "memory", "WS_BROADCAST_OVERFLOW": "spill"}, []string{"requires redis.addr"}},
+		{"reports every error", map[string]string{"DB_TYPE": "memory", "PORT": "70000", "WS_SLOW_CONSUMER": "ignore", "CORS_ALLOWED_ORIGINS": "*", "CORS_ALLOW_CREDENTIALS": "true"},
+			[]string{"server.port", "hub.slow_consumer", "cors:"}},
+		{"null origin with credentials", map[string]string{"DB_TYPE": "memory", "CORS_ALLOWED_ORIGINS": "http://localhost:*,null", "CORS_ALLOW_CREDENTIALS": "true"}, []string{`origin "null"`}},
+		{"bad trusted proxy", map[string]string{"DB_TYPE": "memory", "TRUSTED_PROXIES": "10.0.0.0/8,lb.internal"}, []string{`server.trusted_proxies: invalid IP or CIDR "lb.internal"`}},
+		{"bad tenant id", map[string]string{"DB_TYPE": "memory", "TENANT_DATABASES": "Bad Tenant=x"}, []string{"invalid tenant id"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, _, err := load(nil, envFrom(tt.env))
+			if err == nil {
+				t.Fatal("expected validation error")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(err.Error(), want) {
+					t.Errorf("error %q does not mention %q", err, want)
+				}
+			}
+		})
+	}
+
+	if _, _, err := load(nil, envFrom(map[string]string{"DB_TYPE": "memory"})); err != nil {
+		t.Fatalf("memory needs no dsn: %v", err)
+	}
+	// origin mặc định phải dùng được cùng credentials
+	if _, _, err := load(nil, envFrom(map[string]string{"DB_TYPE": "memory", "CORS_ALLOW_CREDENTIALS": "true"})); err != nil {
+		t.Fatalf("default origins with credentials: %v", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg, _, err := load(nil, envFrom(map[string]string{
+		"DB_DSN":           "host=db user=app password='p@ss word' dbname=items",
+		"TENANT_DATABASES": "acme=postgres://app:tenantpw@db/acme;globex=root:mysqlpw@tcp(db:3306)/globex",
+		"AUTH_API_KEYS":    "supersecretkey:alice",
+		"AUTH_JWT_SECRET":  "jwtsecret",
+		"REDIS_ADDR":       "redis:6379",
+		"REDIS_PASSWORD":   "redispw",
+	}))
+	if err != nil {
+		t.Fatal(err)
+	}
+	out := cfg.Redacted()
+	for _, secret := range []string{"p@ss word", "tenantpw", "mysqlpw", "supersecretkey", "jwtsecret", "redispw"} {
+		if strings.Contains(out, secret) {
+			t.Errorf("secret %q leaked:\n%s", secret, out)
+		}
+	}
+	for _, want := range []string{"password=*** dbname=items", "postgres://app:***@db/acme", "root:***@tcp(db:3306)/globex", "addr: redis:6379"} {
+		if !strings.Contains(out, want) {
+			t.Errorf("redacted output missing %q:\n%s", want, out)
+		}
+	}
+	// cấu hình gốc không bị sửa
+	if cfg.Auth.JWTSecret != "jwtsecret" || cfg.Database.TenantDatabases["acme"] != "postgres://app:tenantpw@db/acme" {
+		t.Error("Redacted modified the config")
+	}
+}
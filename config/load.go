@@ -0,0 +1,326 @@
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// Load nạp cấu hình từ mặc định, file, môi trường và flag trong args
+// (thường là os.Args[1:]) rồi Validate. Trả về các argument còn lại sau
+// flag, ví dụ subcommand "migrate up".
+func Load(args []string) (*Config, []string, error) {
+	return load(args, os.LookupEnv)
+}
+
+func load(args []string, lookupEnv func(string) (string, bool)) (*Config, []string, error) {
+	cfg := Default()
+	fields := cfg.fields()
+
+	// flag được parse trước để lấy --config, nhưng chỉ được áp dụng sau cùng
+	fs := flag.NewFlagSet("server", flag.ContinueOnError)
+	path := fs.String("config", "", "config file (.yaml, .yml or .toml), env CONFIG_FILE")
+	var flagged []flagValue
+	for _, f := range fields {
+		record := func(s string) error {
+			flagged = append(flagged, flagValue{field: f, value: s})
+			return nil
+		}
+		if f.value.Kind() == reflect.Bool {
+			fs.BoolFunc(f.flagName(), f.usage(), record)
+		} else {
+			fs.Func(f.flagName(), f.usage(), record)
+		}
+	}
+	if err := fs.Parse(args); err != nil {
+		return nil, nil, err
+	}
+
+	if *path == "" {
+		*path, _ = lookupEnv("CONFIG_FILE")
+	}
+	if *path != "" {
+		if err := loadFile(*path, fields); err != nil {
+			return nil, nil, err
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		// biến rỗng coi như không đặt
+		if v, ok := lookupEnv(f.env); ok && v != "" {
+			if err := setString(f.value, v); err != nil {
+				return nil, nil, fmt.Errorf("env %s: %w", f.env, err)
+			}
+		}
+	}
+
+	for _, fv := range flagged {
+		if err := setString(fv.field.value, fv.value); err != nil {
+			return nil, nil, fmt.Errorf("flag -%s: %w", fv.field.flagName(), err)
+		}
+	}
+
+	if err := cfg.Validate(); err != nil {
+		return nil, nil, fmt.Errorf("invalid config:\n%w", err)
+	}
+	return cfg, fs.Args(), nil
+}
+
+// field là một giá trị lá trong Config (không phải struct lồng nhau).
+type field struct {
+	value  reflect.Value
+	key    []string // theo tag yaml, ví dụ ["database", "dsn"]
+	env    string
+	secret string
+}
+
+type flagValue struct {
+	field field
+	value string
+}
+
+func (f field) path() string { return strings.Join(f.key, ".") }
+
+func (f field) flagName() string {
+	return strings.ReplaceAll(strings.Join(f.key, "-"), "_", "-")
+}
+
+func (f field) usage() string {
+	if f.env == "" {
+		return f.path()
+	}
+	return fmt.Sprintf("%s, env %s", f.path(), f.env)
+}
+
+// fields liệt kê mọi field lá của cfg; value trỏ thẳng vào cfg.
+func (c *Config) fields() []field {
+	var out []field
+	var walk func(v reflect.Value, key []string)
+	walk = func(v reflect.Value, key []string) {
+		t := v.Type()
+		for i := 0; i < t.NumField(); i++ {
+			sf := t.Field(i)
+			name, _, _ := strings.Cut(sf.Tag.Get("yaml"), ",")
+			k := append(append([]string(nil), key...), name)
+			if sf.Type.Kind() == reflect.Struct && sf.Type != durationType {
+				walk(v.Field(i), k)
+				continue
+			}
+			out = append(out, field{
+				value:  v.Field(i),
+				key:    k,
+				env:    sf.Tag.Get("env"),
+				secret: sf.Tag.Get("secret"),
+			})
+		}
+	}
+	walk(reflect.ValueOf(c).Elem(), nil)
+	return out
+}
+
+var durationType = reflect.TypeOf(time.Duration(0))
+
+// setString gán giá trị dạng chuỗi (env, flag): list ngăn cách bằng ',',
+// map dạng "k=v;k2=v2" (chỉ '=' đầu tiên tách key).
+func setString(v reflect.Value, s string) error {
+	switch {
+	case v.Type() == durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+	case v.Kind() == reflect.String:
+		v.SetString(s)
+	case v.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case v.Kind() == reflect.Int:
+		n, err := strconv.Atoi(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(n))
+	case v.Kind() == reflect.Slice:
+		v.Set(reflect.ValueOf(splitList(s)))
+	case v.Kind() == reflect.Map:
+		m, err := parseMap(s)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(m))
+	default:
+		return fmt.Errorf("unsupported config type %s", v.Type())
+	}
+	return nil
+}
+
+func splitList(s string) []string {
+	out := []string{}
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+// parseMap tách theo ';' vì DSN có thể chứa ',' và '='.
+func parseMap(s string) (map[string]string, error) {
+	out := make(map[string]string)
+	for _, entry := range strings.Split(s, ";") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+		k, v, ok := strings.Cut(strings.TrimSpace(entry), "=")
+		if !ok || strings.TrimSpace(k) == "" {
+			return nil, fmt.Errorf("invalid entry %q, want key=value", entry)
+		}
+		out[strings.TrimSpace(k)] = strings.TrimSpace(v)
+	}
+	return out, nil
+}
+
+// loadFile đọc file YAML hoặc TOML (theo đuôi file). Key không có trong
+// Config là lỗi để gõ sai tên không bị bỏ qua âm thầm.
+func loadFile(path string, fields []field) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return fmt.Errorf("config file: %w", err)
+	}
+	var doc map[string]any
+	switch ext := strings.ToLower(filepath.Ext(path)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &doc)
+	case ".toml":
+		err = toml.Unmarshal(data, &doc)
+	default:
+		return fmt.Errorf("config file %s: unsupported format %q, want .yaml, .yml or .toml", path, ext)
+	}
+	if err != nil {
+		return fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	known := make(map[string]bool)
+	for _, f := range fields {
+		known[f.path()] = true
+		for i := 1; i < len(f.key); i++ {
+			known[strings.Join(f.key[:i], ".")+"."] = true
+		}
+		raw, ok := lookup(doc, f.key)
+		if !ok || raw == nil {
+			continue
+		}
+		if err := setRaw(f.value, raw); err != nil {
+			return fmt.Errorf("config file %s: %s: %w", path, f.path(), err)
+		}
+	}
+	if unknown := unknownKeys(doc, "", known); len(unknown) > 0 {
+		sort.Strings(unknown)
+		return fmt.Errorf("config file %s: unknown keys: %s", path, strings.Join(unknown, ", "))
+	}
+	return nil
+}
+
+func lookup(doc map[string]any, key []string) (any, bool) {
+	var cur any = doc
+	for _, k := range key {
+		m, ok := asMap(cur)
+		if !ok {
+			return nil, false
+		}
+		if cur, ok = m[k]; !ok {
+			return nil, false
+		}
+	}
+	return cur, true
+}
+
+func unknownKeys(doc map[string]any, prefix string, known map[string]bool) []string {
+	var out []string
+	for k, v := range doc {
+		path := prefix + k
+		switch {
+		case known[path]:
+		case known[path+"."]:
+			if m, ok := asMap(v); ok {
+				out = append(out, unknownKeys(m, path+".", known)...)
+			} else {
+				out = append(out, path)
+			}
+		default:
+			out = append(out, path)
+		}
+	}
+	return out
+}
+
+func asMap(v any) (map[string]any, bool) {
+	switch m := v.(type) {
+	case map[string]any:
+		return m, true
+	case map[any]any:
+		out := make(map[string]any, len(m))
+		for k, v := range m {
+			out[fmt.Sprint(k)] = v
+		}
+		return out, true
+	}
+	return nil, false
+}
+
+// setRaw gán giá trị đã decode từ file: list và map dùng kiểu của file,
+// giá trị đơn được đưa về chuỗi rồi parse như env (duration viết "10s").
+func setRaw(v reflect.Value, raw any) error {
+	switch v.Kind() {
+	case reflect.Slice:
+		if s, ok := raw.(string); ok {
+			v.Set(reflect.ValueOf(splitList(s)))
+			return nil
+		}
+		items, ok := raw.([]any)
+		if !ok {
+			return errors.New("want a list")
+		}
+		list := make([]string, len(items))
+		for i, item := range items {
+			list[i] = fmt.Sprint(item)
+		}
+		v.Set(reflect.ValueOf(list))
+		return nil
+	case reflect.Map:
+		m, ok := asMap(raw)
+		if !ok {
+			return errors.New("want a map")
+		}
+		out := make(map[string]string, len(m))
+		for k, val := range m {
+			out[k] = fmt.Sprint(val)
+		}
+		v.Set(reflect.ValueOf(out))
+		return nil
+	}
+	if _, ok := asMap(raw); ok {
+		return errors.New("want a single value, got a map")
+	}
+	if _, ok := raw.([]any); ok {
+		return errors.New("want a single value, got a list")
+	}
+	return setString(v, fmt.Sprint(raw))
+}
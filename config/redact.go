@@ -0,0 +1,82 @@
+package config
+
+import (
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/goccy/go-yaml"
+)
+
+const redacted = "***"
+
+// Redacted trả về cấu hình dạng YAML (cùng key với file config), secret
+// được che: mật khẩu trong DSN/URI thành ***, API key và JWT secret bị che
+// toàn bộ.
+func (c *Config) Redacted() string {
+	out := *c
+	for _, f := range out.fields() {
+		switch f.secret {
+		case "true":
+			if f.value.String() != "" {
+				f.value.SetString(redacted)
+			}
+		case "dsn":
+			redactDSNs(f.value)
+		}
+	}
+	data, err := yaml.Marshal(&out)
+	if err != nil {
+		return "error: " + err.Error()
+	}
+	return string(data)
+}
+
+func (c *Config) String() string {
+	return c.Redacted()
+}
+
+// redactDSNs che một DSN hoặc map tenant → DSN; map được thay bằng bản sao
+// để không sửa cấu hình gốc.
+func redactDSNs(v reflect.Value) {
+	if v.Kind() == reflect.String {
+		v.SetString(RedactDSN(v.String()))
+		return
+	}
+	m, ok := v.Interface().(map[string]string)
+	if !ok || m == nil {
+		return
+	}
+	out := make(map[string]string, len(m))
+	for k, dsn := range m {
+		out[k] = RedactDSN(dsn)
+	}
+	v.Set(reflect.ValueOf(out))
+}
+
+var (
+	// key=value DSN của Postgres: password=... hoặc password='...'
+	kvPassword = regexp.MustCompile(`(?i)(password\s*=\s*)('(?:[^'\\]|\\.)*'|\S+)`)
+	// DSN của MySQL: user:password@tcp(host)/db
+	mysqlPassword = regexp.MustCompile(`^([^:@/]*):([^@]*)@`)
+)
+
+// RedactDSN che mật khẩu trong DSN dạng URL (postgres://, mongodb://),
+// key=value (Postgres) hoặc user:pass@tcp(...) (MySQL).
+func RedactDSN(dsn string) string {
+	if strings.Contains(dsn, "://") {
+		if u, err := url.Parse(dsn); err == nil && u.User != nil {
+			if _, ok := u.User.Password(); ok {
+				u.User = url.UserPassword(u.User.Username(), redacted)
+				// url escape '*' trong userinfo
+				return strings.Replace(u.String(), ":%2A%2A%2A@", ":"+redacted+"@", 1)
+			}
+			return dsn
+		}
+	}
+	if kvPassword.MatchString(dsn) {
+		return kvPassword.ReplaceAllString(dsn, "${1}"+redacted)
+	}
+	return mysqlPassword.ReplaceAllString(dsn, "${1}:"+redacted+"@")
+}
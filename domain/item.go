@@ -1,13 +1,18 @@
 package domain
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type Item struct {
-	ID        string    `json:"id"         bson:"_id"`
-	Name      string    `json:"name"        bson:"name"`
-	Content   string    `json:"content"     bson:"content"`
-	CreatedAt time.Time `json:"created_at"  bson:"created_at"`
-	UpdatedAt time.Time `json:"updated_at"  bson:"updated_at"`
+	ID        string    `json:"id"                  bson:"_id"`
+	Name      string    `json:"name"                bson:"name"`
+	Content   string    `json:"content"             bson:"content"`
+	OwnerID   string    `json:"owner_id,omitempty"  bson:"owner_id"`
+	TenantID  string    `json:"tenant_id,omitempty" bson:"tenant_id"`
+	CreatedAt time.Time `json:"created_at"          bson:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"          bson:"updated_at"`
 }
 
 type ListParams struct {
@@ -15,6 +20,12 @@ type ListParams struct {
 	PageSize int    `form:"page_size" json:"page_size"`
 	SortBy   string `form:"sort_by"   json:"sort_by"`
 	SortDir  string `form:"sort_dir"  json:"sort_dir"`
+	Cursor   string `form:"cursor"    json:"cursor"`
+
+	Q             string    `form:"q"              json:"q"`
+	CreatedAfter  time.Time `form:"created_after"  json:"created_after"  time_format:"2006-01-02T15:04:05Z07:00"`
+	CreatedBefore time.Time `form:"created_before" json:"created_before" time_format:"2006-01-02T15:04:05Z07:00"`
+	UpdatedAfter  time.Time `form:"updated_after"  json:"updated_after"  time_format:"2006-01-02T15:04:05Z07:00"`
 }
 
 func (p *ListParams) SetDefaults() {
@@ -30,6 +41,7 @@ func (p *ListParams) SetDefaults() {
 	if p.SortDir == "" {
 		p.SortDir = "desc"
 	}
+	p.Q = strings.TrimSpace(p.Q)
 }
 
 type ListResult struct {
@@ -38,4 +50,15 @@ type ListResult struct {
 	Page       int     `json:"page"`
 	PageSize   int     `json:"page_size"`
 	TotalPages int     `json:"total_pages"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	PrevCursor string  `json:"prev_cursor,omitempty"`
+}
+
+type ItemPatch struct {
+	Name    *string `json:"name"`
+	Content *string `json:"content"`
+}
+
+func (p ItemPatch) IsEmpty() bool {
+	return p.Name == nil && p.Content == nil
 }
@@ -0,0 +1,29 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Tên event ghi vào outbox, trùng với EventType mà hub gửi cho client.
+const (
+	EventItemCreated = "ITEM_CREATED"
+	EventItemUpdated = "ITEM_UPDATED"
+	EventItemDeleted = "ITEM_DELETED"
+)
+
+// OutboxEvent là event được ghi cùng transaction với thay đổi của item,
+// chờ relay đẩy ra hub.
+type OutboxEvent struct {
+	ID        string          `json:"id"`
+	Type      string          `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	CreatedAt time.Time       `json:"created_at"`
+}
+
+// ChangeEvent là thay đổi đọc trực tiếp từ DB (trigger, change stream,
+// polling), gồm cả thay đổi không đi qua service.
+type ChangeEvent struct {
+	Type    string          `json:"type"`
+	Payload json.RawMessage `json:"payload"`
+}
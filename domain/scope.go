@@ -0,0 +1,49 @@
+package domain
+
+import "regexp"
+
+// Scope giới hạn những item một caller được thấy và sửa. Tenant luôn được
+// so khớp tuyệt đối để dữ liệu không bao giờ lọt sang tenant khác:
+//   - TenantID khác rỗng: mọi item thuộc tenant/workspace đó
+//   - chỉ có OwnerID: item do caller tạo và không thuộc tenant nào
+//   - rỗng: không giới hạn (auth tắt, hoặc tác vụ nội bộ như change feed)
+//
+// Thấy item chưa đủ để sửa/xoá nó, xem CanModify.
+type Scope struct {
+	OwnerID  string
+	TenantID string
+	// Admin: admin của tenant, được sửa/xoá item của thành viên khác
+	Admin bool
+}
+
+func (s Scope) IsZero() bool {
+	return s.OwnerID == "" && s.TenantID == ""
+}
+
+// Allows báo caller có được thấy item có ownerID/tenantID này không.
+func (s Scope) Allows(ownerID, tenantID string) bool {
+	if s.IsZero() {
+		return true
+	}
+	if tenantID != s.TenantID {
+		return false
+	}
+	return s.TenantID != "" || ownerID == s.OwnerID
+}
+
+// CanModify báo caller có được sửa/xoá item không: phải thấy được item, và
+// là người tạo ra nó trừ khi scope rỗng hoặc là admin của tenant.
+func (s Scope) CanModify(ownerID, tenantID string) bool {
+	if !s.Allows(ownerID, tenantID) {
+		return false
+	}
+	return s.IsZero() || s.Admin || ownerID == s.OwnerID
+}
+
+var tenantIDPattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_-]{0,62}$`)
+
+// ValidTenantID giới hạn ký tự của tenant ID vì nó còn được dùng trong
+// subdomain, tên room của hub và id event outbox.
+func ValidTenantID(id string) bool {
+	return tenantIDPattern.MatchString(id)
+}
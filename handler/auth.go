@@ -0,0 +1,109 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/JIeeiroSst/hub/auth"
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+const (
+	// wsCloseUnauthenticated là close code riêng (dải 4000-4999) khi xác thực
+	// bằng message đầu tiên thất bại.
+	wsCloseUnauthenticated = 4401
+	wsAuthTimeout          = 5 * time.Second
+)
+
+// RequireAuth từ chối request không có credential hợp lệ với 401.
+// authn nil nghĩa là auth bị tắt, mọi request đều được cho qua.
+func RequireAuth(authn auth.Authenticator) gin.HandlerFunc {
+	return authenticate(authn, true)
+}
+
+// OptionalAuth như RequireAuth nhưng cho qua request không gửi credential,
+// dùng cho /ws để client xác thực bằng message đầu tiên. Credential sai vẫn bị 401.
+func OptionalAuth(authn auth.Authenticator) gin.HandlerFunc {
+	return authenticate(authn, false)
+}
+
+func authenticate(authn auth.Authenticator, required bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if authn == nil {
+			c.Next()
+			return
+		}
+
+		credential := credentialFrom(c.Request)
+		if credential == "" && !required {
+			c.Next()
+			return
+		}
+
+		principal, err := authn.Authenticate(c.Request.Context(), credential)
+		if err != nil {
+			c.Header("WWW-Authenticate", `Bearer realm="api"`)
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+		c.Next()
+	}
+}
+
+// credentialFrom đọc credential theo thứ tự: Authorization (Bearer/ApiKey),
+// X-API-Key, rồi ?access_token= cho WebSocket/SSE vì trình duyệt không gắn
+// được header cho hai loại kết nối này.
+func credentialFrom(r *http.Request) string {
+	if v := r.Header.Get("Authorization"); v != "" {
+		scheme, token, ok := strings.Cut(v, " ")
+		if ok && (strings.EqualFold(scheme, "Bearer") || strings.EqualFold(scheme, "ApiKey")) {
+			return strings.TrimSpace(token)
+		}
+		return ""
+	}
+	if v := r.Header.Get("X-API-Key"); v != "" {
+		return v
+	}
+	if websocket.IsWebSocketUpgrade(r) || strings.Contains(r.Header.Get("Accept"), "text/event-stream") {
+		return r.URL.Query().Get("access_token")
+	}
+	return ""
+}
+
+// authMessage là frame đầu tiên client gửi khi chưa xác thực lúc upgrade.
+type authMessage struct {
+	Type  string `json:"type"`
+	Token string `json:"token"`
+}
+
+// authenticateFirstMessage đợi frame {"type":"auth","token":"..."} trong
+// wsAuthTimeout. Thất bại thì gửi close frame 4401 và đóng kết nối.
+func (h *ItemHandler) authenticateFirstMessage(c *gin.Context, conn *websocket.Conn) (*auth.Principal, error) {
+	conn.SetReadDeadline(time.Now().Add(wsAuthTimeout))
+	defer conn.SetReadDeadline(time.Time{})
+
+	var msg authMessage
+	err := conn.ReadJSON(&msg)
+	if err == nil && msg.Type != "auth" {
+		err = fmt.Errorf("%w: first message must be auth", domain.ErrUnauthenticated)
+	}
+	var principal *auth.Principal
+	if err == nil {
+		principal, err = h.authn.Authenticate(c.Request.Context(), msg.Token)
+	}
+	if err != nil {
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(wsCloseUnauthenticated, "unauthenticated"),
+			time.Now().Add(time.Second))
+		conn.Close()
+		return nil, err
+	}
+	return principal, nil
+}
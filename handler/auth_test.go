@@ -0,0 +1,131 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	"github.com/JIeeiroSst/hub/auth"
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+func newAuthServer(t *testing.T) (*httptest.Server, *ws.Hub) {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	hub := ws.NewHub()
+	go hub.Run()
+
+	authn := auth.Chain(auth.NewAPIKeyAuthenticator(map[string]auth.Principal{"secret-key": {ID: "alice"}}))
+	h := NewItemHandler(nil, hub, authn, nil, nil)
+
+	r := gin.New()
+	r.GET("/whoami", RequireAuth(authn), func(c *gin.Context) {
+		c.String(http.StatusOK, auth.FromContext(c.Request.Context()).ID)
+	})
+	r.GET("/ws", OptionalAuth(authn), h.WebSocket)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv, hub
+}
+
+func TestRequireAuth(t *testing.T) {
+	srv, _ := newAuthServer(t)
+
+	cases := []struct {
+		name   string
+		header string
+		value  string
+		want   int
+	}{
+		{"missing", "", "", http.StatusUnauthorized},
+		{"bearer", "Authorization", "Bearer secret-key", http.StatusOK},
+		{"x-api-key", "X-API-Key", "secret-key", http.StatusOK},
+		{"wrong key", "Authorization", "Bearer nope", http.StatusUnauthorized},
+		{"basic scheme", "Authorization", "Basic secret-key", http.StatusUnauthorized},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, srv.URL+"/whoami", nil)
+		if tc.header != "" {
+			req.Header.Set(tc.header, tc.value)
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != tc.want {
+			t.Fatalf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+
+	// query token chỉ được chấp nhận cho WebSocket/SSE
+	resp, err := http.Get(srv.URL + "/whoami?access_token=secret-key")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("query token on REST: status %d, want 401", resp.StatusCode)
+	}
+}
+
+func TestWebSocketAuth(t *testing.T) {
+	srv, hub := newAuthServer(t)
+	base := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	waitClients := func(n int) {
+		t.Helper()
+		deadline := time.Now().Add(2 * time.Second)
+		for hub.ClientCount() != n {
+			if time.Now().After(deadline) {
+				t.Fatalf("hub has %d clients, want %d", hub.ClientCount(), n)
+			}
+			time.Sleep(5 * time.Millisecond)
+		}
+	}
+
+	// credential sai lúc upgrade: từ chối ngay bằng 401
+	_, resp, err := websocket.DefaultDialer.Dial(base+"?access_token=nope", nil)
+	if err == nil || resp == nil || resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("bad query token: err %v, resp %v", err, resp)
+	}
+
+	byQuery, _, err := websocket.DefaultDialer.Dial(base+"?access_token=secret-key", nil)
+	if err != nil {
+		t.Fatalf("query token: %v", err)
+	}
+	defer byQuery.Close()
+	waitClients(1)
+
+	byMessage, _, err := websocket.DefaultDialer.Dial(base, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer byMessage.Close()
+	if err := byMessage.WriteJSON(authMessage{Type: "auth", Token: "secret-key"}); err != nil {
+		t.Fatalf("write auth: %v", err)
+	}
+	waitClients(2)
+
+	rejected, _, err := websocket.DefaultDialer.Dial(base, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	defer rejected.Close()
+	if err := rejected.WriteJSON(authMessage{Type: "auth", Token: "nope"}); err != nil {
+		t.Fatalf("write auth: %v", err)
+	}
+	rejected.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err = rejected.ReadMessage()
+	if !websocket.IsCloseError(err, wsCloseUnauthenticated) {
+		t.Fatalf("bad first message: got %v, want close %d", err, wsCloseUnauthenticated)
+	}
+	if hub.ClientCount() != 2 {
+		t.Fatalf("rejected client registered: %d clients", hub.ClientCount())
+	}
+}
@@ -0,0 +1,143 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CORSConfig là chính sách origin dùng chung cho CORS của REST/SSE và
+// CheckOrigin của WebSocket, để hai đường không lệch nhau.
+type CORSConfig struct {
+	// AllowedOrigins dạng "https://app.example.com"; host được bắt đầu bằng
+	// "*." (subdomain bất kỳ), port được là "*"; "*" là mọi origin, "null"
+	// là origin mờ (file://, iframe sandbox, data: URL).
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	ExposedHeaders   []string
+	AllowCredentials bool
+	// MaxAge là thời gian trình duyệt cache kết quả preflight.
+	MaxAge time.Duration
+}
+
+// Validate từ chối "*" và "null" đi cùng credentials: khi đó mọi trang web
+// đều gọi được API bằng cookie/credential của người dùng. Trang bất kỳ tự
+// lấy được origin "null" bằng iframe sandbox hoặc data: URL.
+func (cfg *CORSConfig) Validate() error {
+	if cfg.AllowCredentials {
+		for _, o := range cfg.AllowedOrigins {
+			switch o {
+			case "*":
+				return errors.New("cors: wildcard origin \"*\" cannot be used with credentials")
+			case "null":
+				return errors.New("cors: origin \"null\" cannot be used with credentials")
+			}
+		}
+	}
+	return nil
+}
+
+// Middleware trả header CORS cho origin được phép và trả lời preflight.
+// Origin không được phép không nhận header nào (trình duyệt sẽ chặn), riêng
+// preflight bị 403.
+func (cfg *CORSConfig) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		origin := c.GetHeader("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		preflight := c.Request.Method == http.MethodOptions && c.GetHeader("Access-Control-Request-Method") != ""
+
+		c.Writer.Header().Add("Vary", "Origin")
+		if !cfg.allowed(origin) {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Origin", origin)
+		if cfg.AllowCredentials {
+			c.Header("Access-Control-Allow-Credentials", "true")
+		}
+		if !preflight {
+			if len(cfg.ExposedHeaders) > 0 {
+				c.Header("Access-Control-Expose-Headers", strings.Join(cfg.ExposedHeaders, ", "))
+			}
+			c.Next()
+			return
+		}
+
+		c.Header("Access-Control-Allow-Methods", strings.Join(cfg.AllowedMethods, ", "))
+		c.Header("Access-Control-Allow-Headers", strings.Join(cfg.AllowedHeaders, ", "))
+		if cfg.MaxAge > 0 {
+			c.Header("Access-Control-Max-Age", strconv.Itoa(int(cfg.MaxAge.Seconds())))
+		}
+		c.AbortWithStatus(http.StatusNoContent)
+	}
+}
+
+// CheckOrigin dùng cho websocket.Upgrader: cho qua client không gửi Origin
+// (không phải trình duyệt), cùng origin với server, hoặc origin trong allow-list.
+func (cfg *CORSConfig) CheckOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	if origin == "" {
+		return true
+	}
+	if _, hostport, ok := strings.Cut(origin, "://"); ok && strings.EqualFold(hostport, r.Host) {
+		return true
+	}
+	return cfg.allowed(origin)
+}
+
+func (cfg *CORSConfig) allowed(origin string) bool {
+	for _, pattern := range cfg.AllowedOrigins {
+		if originMatches(pattern, origin) {
+			return true
+		}
+	}
+	return false
+}
+
+func originMatches(pattern, origin string) bool {
+	if pattern == "*" || strings.EqualFold(pattern, origin) {
+		return true
+	}
+	pScheme, pHost, pPort, ok := splitOrigin(pattern)
+	if !ok {
+		return false
+	}
+	oScheme, oHost, oPort, ok := splitOrigin(origin)
+	if !ok || !strings.EqualFold(pScheme, oScheme) {
+		return false
+	}
+	if pPort != "*" && pPort != oPort {
+		return false
+	}
+	if suffix, ok := strings.CutPrefix(pHost, "*."); ok {
+		return strings.HasSuffix(strings.ToLower(oHost), "."+strings.ToLower(suffix))
+	}
+	return strings.EqualFold(pHost, oHost)
+}
+
+// splitOrigin tách "scheme://host[:port]"; không dùng url.Parse vì pattern
+// có thể có port "*".
+func splitOrigin(s string) (scheme, host, port string, ok bool) {
+	scheme, hostport, ok := strings.Cut(s, "://")
+	if !ok || hostport == "" || strings.ContainsAny(hostport, "/?#@") {
+		return "", "", "", false
+	}
+	host = hostport
+	if i := strings.LastIndex(hostport, ":"); i >= 0 && !strings.HasSuffix(hostport, "]") {
+		host, port = hostport[:i], hostport[i+1:]
+	}
+	return scheme, host, port, true
+}
@@ -0,0 +1,122 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+func TestOriginMatches(t *testing.T) {
+	cases := []struct {
+		pattern, origin string
+		want            bool
+	}{
+		{"https://app.example.com", "https://app.example.com", true},
+		{"https://app.example.com", "http://app.example.com", false},
+		{"https://app.example.com", "https://app.example.com:8443", false},
+		{"https://*.example.com", "https://a.b.example.com", true},
+		{"https://*.example.com", "https://example.com", false},
+		{"https://*.example.com", "https://evilexample.com", false},
+		{"http://localhost:*", "http://localhost:5500", true},
+		{"http://localhost:*", "http://localhost.evil.com:5500", false},
+		{"null", "null", true},
+		{"*", "https://anything.test", true},
+	}
+	for _, tc := range cases {
+		if got := originMatches(tc.pattern, tc.origin); got != tc.want {
+			t.Errorf("originMatches(%q, %q) = %v, want %v", tc.pattern, tc.origin, got, tc.want)
+		}
+	}
+
+	bad := CORSConfig{AllowedOrigins: []string{"*"}, AllowCredentials: true}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("wildcard origin with credentials: expected error")
+	}
+	bad = CORSConfig{AllowedOrigins: []string{"http://localhost:*", "null"}, AllowCredentials: true}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("null origin with credentials: expected error")
+	}
+}
+
+func TestCORSMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	cfg := &CORSConfig{
+		AllowedOrigins:   []string{"https://app.example.com"},
+		AllowedMethods:   []string{"GET", "POST"},
+		AllowedHeaders:   []string{"Authorization"},
+		ExposedHeaders:   []string{"X-Event-Seq"},
+		AllowCredentials: true,
+	}
+	r := gin.New()
+	r.Use(cfg.Middleware())
+	r.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method, origin string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/items", nil)
+		req.Header.Set("Origin", origin)
+		if method == http.MethodOptions {
+			req.Header.Set("Access-Control-Request-Method", "GET")
+		}
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	w := do(http.MethodGet, "https://app.example.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "https://app.example.com" ||
+		w.Header().Get("Access-Control-Allow-Credentials") != "true" ||
+		w.Header().Get("Access-Control-Expose-Headers") != "X-Event-Seq" {
+		t.Fatalf("allowed origin: headers %v", w.Header())
+	}
+
+	w = do(http.MethodOptions, "https://app.example.com")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "GET, POST" {
+		t.Fatalf("preflight: status %d, headers %v", w.Code, w.Header())
+	}
+
+	if w := do(http.MethodGet, "https://evil.test"); w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Fatalf("disallowed origin got CORS headers: %v", w.Header())
+	}
+	if w := do(http.MethodOptions, "https://evil.test"); w.Code != http.StatusForbidden {
+		t.Fatalf("disallowed preflight: status %d, want 403", w.Code)
+	}
+}
+
+func TestWebSocketCheckOrigin(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := ws.NewHub()
+	go hub.Run()
+	cfg := &CORSConfig{AllowedOrigins: []string{"https://app.example.com"}}
+	h := NewItemHandler(nil, hub, nil, nil, cfg)
+
+	r := gin.New()
+	r.GET("/ws", h.WebSocket)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws"
+
+	for origin, want := range map[string]bool{
+		"":                        true, // không phải trình duyệt
+		srv.URL:                   true, // cùng origin
+		"https://app.example.com": true,
+		"https://evil.test":       false,
+	} {
+		header := http.Header{}
+		if origin != "" {
+			header.Set("Origin", origin)
+		}
+		conn, _, err := websocket.DefaultDialer.Dial(url, header)
+		if (err == nil) != want {
+			t.Fatalf("origin %q: dial err = %v, want allowed=%v", origin, err, want)
+		}
+		if conn != nil {
+			conn.Close()
+		}
+	}
+}
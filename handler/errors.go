@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+const (
+	codeNotFound        = "NOT_FOUND"
+	codeConflict        = "CONFLICT"
+	codeValidation      = "VALIDATION_FAILED"
+	codeUnavailable     = "SERVICE_UNAVAILABLE"
+	codeUnauthenticated = "UNAUTHENTICATED"
+	codeForbidden       = "FORBIDDEN"
+	codeRateLimited     = "RATE_LIMITED"
+	codeInternal        = "INTERNAL_ERROR"
+)
+
+// respondError là nơi duy nhất map lỗi từ service/repository sang HTTP status
+// và error code ổn định cho client. Lỗi không map được có thể chứa SQL, tên
+// bảng hay host của DB nên chỉ được ghi log, client nhận message cố định.
+func respondError(c *gin.Context, err error) {
+	status, code, message := http.StatusInternalServerError, codeInternal, err.Error()
+
+	switch {
+	case errors.Is(err, domain.ErrNotFound):
+		status, code, message = http.StatusNotFound, codeNotFound, "item not found"
+	case errors.Is(err, domain.ErrConflict):
+		status, code = http.StatusConflict, codeConflict
+	case errors.Is(err, domain.ErrValidation):
+		status, code = http.StatusBadRequest, codeValidation
+	case errors.Is(err, domain.ErrUnavailable):
+		status, code = http.StatusServiceUnavailable, codeUnavailable
+	case errors.Is(err, domain.ErrUnauthenticated):
+		status, code = http.StatusUnauthorized, codeUnauthenticated
+	case errors.Is(err, domain.ErrForbidden):
+		status, code = http.StatusForbidden, codeForbidden
+	case errors.Is(err, domain.ErrRateLimited):
+		status, code = http.StatusTooManyRequests, codeRateLimited
+	default:
+		log.Printf("[HTTP] %s %s: %v", c.Request.Method, c.FullPath(), err)
+		message = "internal server error"
+	}
+
+	c.JSON(status, gin.H{"error": message, "code": code})
+}
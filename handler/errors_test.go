@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+func TestRespondError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	cases := []struct {
+		name    string
+		err     error
+		status  int
+		message string
+	}{
+		{"not found", fmt.Errorf("get: %w", domain.ErrNotFound), http.StatusNotFound, "item not found"},
+		{"validation", fmt.Errorf("%w: name is required", domain.ErrValidation), http.StatusBadRequest, "name is required"},
+		// lỗi driver không được lộ ra client
+		{"internal", errors.New(`pq: relation "items" does not exist (host=db.internal)`), http.StatusInternalServerError, "internal server error"},
+	}
+	for _, tc := range cases {
+		w := httptest.NewRecorder()
+		c, _ := gin.CreateTestContext(w)
+		c.Request = httptest.NewRequest(http.MethodGet, "/items", nil)
+		respondError(c, tc.err)
+
+		if w.Code != tc.status || !strings.Contains(w.Body.String(), tc.message) {
+			t.Fatalf("%s: got %d %s, want %d with %q", tc.name, w.Code, w.Body.String(), tc.status, tc.message)
+		}
+		if tc.status == http.StatusInternalServerError && strings.Contains(w.Body.String(), "db.internal") {
+			t.Fatalf("%s: response leaks error details: %s", tc.name, w.Body.String())
+		}
+	}
+}
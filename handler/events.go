@@ -0,0 +1,106 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JIeeiroSst/hub/auth"
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+const sseHeartbeat = 15 * time.Second
+
+// Events stream event của hub qua Server-Sent Events cho client không dùng
+// được WebSocket. data là cùng JSON WSEvent như /ws, id là seq của event nên
+// trình duyệt tự gửi Last-Event-ID khi reconnect để replay phần đã lỡ.
+func (h *ItemHandler) Events(c *gin.Context) {
+	// Last-Event-ID ưu tiên hơn ?since= vì do EventSource tự gửi khi reconnect
+	since := parseSince(c.GetHeader("Last-Event-ID"))
+	if since == nil {
+		since = parseSince(c.Query("since"))
+	}
+
+	if err := h.hub.Admit(identity(c)); err != nil {
+		c.Header("Retry-After", retryAfter(wsRetryAfter))
+		respondError(c, err)
+		return
+	}
+
+	flusher, ok := c.Writer.(http.Flusher)
+	if !ok {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "streaming unsupported"})
+		return
+	}
+
+	// stream sống lâu hơn WriteTimeout của http.Server
+	if err := http.NewResponseController(c.Writer).SetWriteDeadline(time.Time{}); err != nil && !errors.Is(err, http.ErrNotSupported) {
+		log.Printf("[SSE] clear write deadline: %v", err)
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // tắt buffer của nginx
+	c.Status(http.StatusOK)
+	fmt.Fprint(c.Writer, "retry: 3000\n\n")
+	flusher.Flush()
+
+	client := ws.NewStreamClient(h.hub, since, auth.ScopeFrom(c.Request.Context()), identity(c))
+	defer client.Close()
+
+	heartbeat := time.NewTicker(sseHeartbeat)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+
+		case message, ok := <-client.Events():
+			if !ok {
+				return
+			}
+			if err := writeSSE(c.Writer, message); err != nil {
+				return
+			}
+			flusher.Flush()
+
+		case <-heartbeat.C:
+			// comment line giữ kết nối qua proxy có idle timeout
+			if _, err := fmt.Fprint(c.Writer, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+// writeSSE ghi một WSEvent đã marshal. Event không có seq (SUBSCRIBED,
+// ERROR...) được gửi không kèm id để không làm hỏng Last-Event-ID.
+func writeSSE(w gin.ResponseWriter, message []byte) error {
+	var head struct {
+		Seq uint64 `json:"seq"`
+	}
+	if json.Unmarshal(message, &head) == nil && head.Seq > 0 {
+		if _, err := fmt.Fprintf(w, "id: %d\n", head.Seq); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "data: %s\n\n", message)
+	return err
+}
+
+func parseSince(v string) *uint64 {
+	since, err := strconv.ParseUint(v, 10, 64)
+	if err != nil {
+		return nil
+	}
+	return &since
+}
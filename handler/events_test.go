@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+type sseMessage struct {
+	id    string
+	event ws.WSEvent
+}
+
+func readSSE(t *testing.T, r *bufio.Reader) sseMessage {
+	t.Helper()
+	var msg sseMessage
+	for {
+		line, err := r.ReadString('\n')
+		if err != nil {
+			t.Fatalf("read sse: %v", err)
+		}
+		line = strings.TrimSuffix(line, "\n")
+		switch {
+		case strings.HasPrefix(line, "id: "):
+			msg.id = strings.TrimPrefix(line, "id: ")
+		case strings.HasPrefix(line, "data: "):
+			if err := json.Unmarshal([]byte(strings.TrimPrefix(line, "data: ")), &msg.event); err != nil {
+				t.Fatalf("decode data: %v", err)
+			}
+		case line == "" && msg.event.Type != "":
+			return msg
+		}
+	}
+}
+
+func TestEventsResumesFromLastEventID(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	hub := ws.NewHub()
+	go hub.Run()
+	h := NewItemHandler(nil, hub, nil, nil, nil)
+
+	r := gin.New()
+	r.GET("/events", h.Events)
+	srv := httptest.NewServer(r)
+	defer srv.Close()
+
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(context.Background(), ws.EventItemCreated, i)
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.LastSeq("") < 3 {
+		if time.Now().After(deadline) {
+			t.Fatal("hub did not record events")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL+"/events", nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("get events: %v", err)
+	}
+	defer resp.Body.Close()
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("content-type = %q", ct)
+	}
+
+	body := bufio.NewReader(resp.Body)
+	for want := uint64(2); want <= 3; want++ {
+		msg := readSSE(t, body)
+		if msg.event.Seq != want || msg.id != strconv.FormatUint(want, 10) {
+			t.Fatalf("got id %s seq %d, want %d", msg.id, msg.event.Seq, want)
+		}
+	}
+
+	hub.Broadcast(context.Background(), ws.EventItemDeleted, "live")
+	if msg := readSSE(t, body); msg.event.Seq != 4 || msg.event.Type != ws.EventItemDeleted {
+		t.Fatalf("live event = %s #%d, want %s #4", msg.event.Type, msg.event.Seq, ws.EventItemDeleted)
+	}
+}
@@ -1,29 +1,46 @@
 package handler
 
 import (
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/gorilla/websocket"
 
+	"github.com/JIeeiroSst/hub/auth"
 	"github.com/JIeeiroSst/hub/domain"
 	"github.com/JIeeiroSst/hub/service"
 	ws "github.com/JIeeiroSst/hub/websocket"
 )
 
-var upgrader = websocket.Upgrader{
-	ReadBufferSize:  1024,
-	WriteBufferSize: 1024,
-	CheckOrigin:     func(r *http.Request) bool { return true },
-}
-
 type ItemHandler struct {
-	svc *service.ItemService
-	hub *ws.Hub
+	svc      *service.ItemService
+	hub      *ws.Hub
+	authn    auth.Authenticator
+	tenants  *TenantResolver
+	upgrader websocket.Upgrader
 }
 
-func NewItemHandler(svc *service.ItemService, hub *ws.Hub) *ItemHandler {
-	return &ItemHandler{svc: svc, hub: hub}
+// authn nil nghĩa là auth bị tắt; tenants nil chỉ dùng tenant của principal;
+// cors nil chỉ cho WebSocket cùng origin với server.
+func NewItemHandler(svc *service.ItemService, hub *ws.Hub, authn auth.Authenticator, tenants *TenantResolver, cors *CORSConfig) *ItemHandler {
+	h := &ItemHandler{
+		svc:     svc,
+		hub:     hub,
+		authn:   authn,
+		tenants: tenants,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  1024,
+			WriteBufferSize: 1024,
+		},
+	}
+	if cors != nil {
+		h.upgrader.CheckOrigin = cors.CheckOrigin
+	}
+	return h
 }
 
 func (h *ItemHandler) Create(c *gin.Context) {
@@ -32,13 +49,13 @@ func (h *ItemHandler) Create(c *gin.Context) {
 		Content string `json:"content"`
 	}
 	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, fmt.Errorf("%w: %v", domain.ErrValidation, err))
 		return
 	}
 
 	item, err := h.svc.Create(c.Request.Context(), req.Name, req.Content)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
 
@@ -51,15 +68,19 @@ func (h *ItemHandler) Create(c *gin.Context) {
 func (h *ItemHandler) List(c *gin.Context) {
 	var params domain.ListParams
 	if err := c.ShouldBindQuery(&params); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		respondError(c, fmt.Errorf("%w: %v", domain.ErrValidation, err))
 		return
 	}
 
+	// seq (của room tenant) đọc trước khi query để client dùng làm ?since=
+	// mà không lỡ event nào
+	seq := h.hub.LastSeq(auth.ScopeFrom(c.Request.Context()).TenantID)
 	result, err := h.svc.List(c.Request.Context(), params)
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+		respondError(c, err)
 		return
 	}
+	c.Header("X-Event-Seq", strconv.FormatUint(seq, 10))
 
 	c.JSON(http.StatusOK, gin.H{
 		"success": true,
@@ -71,20 +92,98 @@ func (h *ItemHandler) GetByID(c *gin.Context) {
 	id := c.Param("id")
 	item, err := h.svc.GetByID(c.Request.Context(), id)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "item not found"})
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": item})
+}
+
+func (h *ItemHandler) Replace(c *gin.Context) {
+	var req struct {
+		Name    string `json:"name"    binding:"required"`
+		Content string `json:"content"`
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		respondError(c, fmt.Errorf("%w: %v", domain.ErrValidation, err))
+		return
+	}
+
+	item, created, err := h.svc.Replace(c.Request.Context(), c.Param("id"), req.Name, req.Content)
+	if err != nil {
+		respondError(c, err)
+		return
+	}
+	status := http.StatusOK
+	if created {
+		status = http.StatusCreated
+	}
+	c.JSON(status, gin.H{"success": true, "data": item})
+}
+
+func (h *ItemHandler) Update(c *gin.Context) {
+	var patch domain.ItemPatch
+	if err := c.ShouldBindJSON(&patch); err != nil {
+		respondError(c, fmt.Errorf("%w: %v", domain.ErrValidation, err))
+		return
+	}
+	item, err := h.svc.Update(c.Request.Context(), c.Param("id"), patch)
+	if err != nil {
+		respondError(c, err)
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"success": true, "data": item})
 }
 
+func (h *ItemHandler) Delete(c *gin.Context) {
+	id := c.Param("id")
+	if err := h.svc.Delete(c.Request.Context(), id); err != nil {
+		respondError(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true, "data": gin.H{"id": id}})
+}
+
 func (h *ItemHandler) WebSocket(c *gin.Context) {
-	conn, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	// kiểm tra trước khi upgrade để còn trả được 429; principal xác thực bằng
+	// message đầu tiên được hub kiểm tra lại lúc đăng ký
+	if err := h.hub.Admit(identity(c)); err != nil {
+		c.Header("Retry-After", retryAfter(wsRetryAfter))
+		respondError(c, err)
+		return
+	}
+
+	conn, err := h.upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		// Upgrader đã tự trả lỗi HTTP (400, hoặc 403 khi origin bị từ chối)
+		log.Printf("[WS] Upgrade failed: %v", err)
+		return
+	}
+
+	// không có credential lúc upgrade thì message đầu tiên phải là auth
+	if h.authn != nil && auth.FromContext(c.Request.Context()) == nil {
+		principal, err := h.authenticateFirstMessage(c, conn)
+		if err != nil {
+			log.Printf("[WS] Auth failed: %v", err)
+			return
+		}
+		c.Request = c.Request.WithContext(auth.WithPrincipal(c.Request.Context(), principal))
+	}
+
+	// tenant resolve sau khi xác thực xong vì principal có thể tới từ message đầu tiên
+	tenant, err := h.tenants.resolve(c.Request, auth.FromContext(c.Request.Context()))
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "websocket upgrade failed"})
+		log.Printf("[WS] Tenant rejected: %v", err)
+		conn.WriteControl(websocket.CloseMessage,
+			websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "tenant rejected"),
+			time.Now().Add(time.Second))
+		conn.Close()
 		return
 	}
+	c.Request = c.Request.WithContext(auth.WithTenant(c.Request.Context(), tenant))
 
-	client := ws.NewClient(h.hub, conn)
+	// ?since=<seq> để nhận lại các event đã lỡ khi reconnect
+	scope := auth.ScopeFrom(c.Request.Context())
+	client := ws.NewClient(h.hub, conn, parseSince(c.Query("since")), scope, identity(c))
 
 	go client.WritePump()
 	go client.ReadPump()
@@ -99,7 +198,9 @@ func (h *ItemHandler) Health(c *gin.Context) {
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{
-		"status":     "healthy",
-		"ws_clients": h.hub.ClientCount(),
+		"status":               "healthy",
+		"ws_clients":           h.hub.ClientCount(),
+		"ws_dropped":           h.hub.Dropped(),
+		"ws_broadcast_dropped": h.hub.BroadcastDropped(),
 	})
 }
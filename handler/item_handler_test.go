@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JIeeiroSst/hub/auth"
+	"github.com/JIeeiroSst/hub/repository"
+	"github.com/JIeeiroSst/hub/service"
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+func newItemServer(t *testing.T) *httptest.Server {
+	t.Helper()
+	gin.SetMode(gin.TestMode)
+	hub := ws.NewHub()
+	go hub.Run()
+
+	repo := repository.NewMemoryStrategy()
+	svc := service.NewItemService(repo, service.NewOutboxRelay(repo, nil))
+	keys, err := auth.ParseAPIKeys("alice-key:alice:acme,bob-key:bob:acme,carol-key:carol:acme:admin,dave-key:dave:globex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	authn := auth.Chain(auth.NewAPIKeyAuthenticator(keys))
+	var tenants *TenantResolver
+	h := NewItemHandler(svc, hub, authn, tenants, nil)
+
+	r := gin.New()
+	v1 := r.Group("/api/v1", RequireAuth(authn), tenants.Middleware())
+	v1.POST("/items", h.Create)
+	v1.GET("/items/:id", h.GetByID)
+	v1.PUT("/items/:id", h.Replace)
+	v1.PATCH("/items/:id", h.Update)
+	v1.DELETE("/items/:id", h.Delete)
+	srv := httptest.NewServer(r)
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestItemMutationsRequireOwner(t *testing.T) {
+	srv := newItemServer(t)
+
+	do := func(method, path, key, body string) *http.Response {
+		t.Helper()
+		req, _ := http.NewRequest(method, srv.URL+path, strings.NewReader(body))
+		req.Header.Set("X-API-Key", key)
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("%s %s: %v", method, path, err)
+		}
+		t.Cleanup(func() { resp.Body.Close() })
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/api/v1/items", "alice-key", `{"name":"alice's"}`)
+	var created struct {
+		Data struct {
+			ID string `json:"id"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil || resp.StatusCode != http.StatusCreated {
+		t.Fatalf("create: status %d, err %v", resp.StatusCode, err)
+	}
+	path := "/api/v1/items/" + created.Data.ID
+
+	cases := []struct {
+		name   string
+		method string
+		key    string
+		body   string
+		want   int
+	}{
+		// cùng tenant thấy được item nhưng không phải người tạo
+		{"member get", http.MethodGet, "bob-key", "", http.StatusOK},
+		{"member patch", http.MethodPatch, "bob-key", `{"name":"bob's"}`, http.StatusForbidden},
+		{"member put", http.MethodPut, "bob-key", `{"name":"bob's"}`, http.StatusForbidden},
+		{"member delete", http.MethodDelete, "bob-key", "", http.StatusForbidden},
+		// tenant khác không biết item tồn tại
+		{"other tenant patch", http.MethodPatch, "dave-key", `{"name":"dave's"}`, http.StatusNotFound},
+		{"other tenant delete", http.MethodDelete, "dave-key", "", http.StatusNotFound},
+		{"owner patch", http.MethodPatch, "alice-key", `{"content":"mine"}`, http.StatusOK},
+		{"admin put", http.MethodPut, "carol-key", `{"name":"moderated"}`, http.StatusOK},
+		{"admin delete", http.MethodDelete, "carol-key", "", http.StatusOK},
+	}
+	for _, tc := range cases {
+		if resp := do(tc.method, path, tc.key, tc.body); resp.StatusCode != tc.want {
+			t.Fatalf("%s: status %d, want %d", tc.name, resp.StatusCode, tc.want)
+		}
+	}
+}
+
+func TestReplaceCreatesMissingItem(t *testing.T) {
+	srv := newItemServer(t)
+
+	put := func(id, body string) int {
+		t.Helper()
+		req, _ := http.NewRequest(http.MethodPut, srv.URL+"/api/v1/items/"+id, strings.NewReader(body))
+		req.Header.Set("X-API-Key", "alice-key")
+		req.Header.Set("Content-Type", "application/json")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("put %s: %v", id, err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	id := "6f1c2a4e-5b7d-4c3e-9a8f-0d1e2f3a4b5c"
+	if got := put(id, `{"name":"first"}`); got != http.StatusCreated {
+		t.Fatalf("put new id: status %d, want %d", got, http.StatusCreated)
+	}
+	if got := put(id, `{"name":"second"}`); got != http.StatusOK {
+		t.Fatalf("put existing id: status %d, want %d", got, http.StatusOK)
+	}
+	if got := put("not-a-uuid", `{"name":"x"}`); got != http.StatusBadRequest {
+		t.Fatalf("put invalid id: status %d, want %d", got, http.StatusBadRequest)
+	}
+}
@@ -0,0 +1,187 @@
+package handler
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JIeeiroSst/hub/auth"
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+const (
+	// DefaultRoute là key của RateLimit áp dụng cho route không có cấu hình riêng.
+	DefaultRoute = "*"
+
+	// rateLimitSweep là chu kỳ dọn bucket đã đầy lại (client không còn gửi request).
+	rateLimitSweep = time.Minute
+	// wsRetryAfter là Retry-After khi vượt giới hạn kết nối: không biết trước
+	// lúc nào kết nối khác đóng nên chỉ gợi ý thời gian chờ.
+	wsRetryAfter = 5 * time.Second
+)
+
+type RateLimit struct {
+	// Rate là số request được nạp lại mỗi giây.
+	Rate float64
+	// Burst là số request tối đa được gửi dồn một lúc.
+	Burst int
+}
+
+// RateLimiter giới hạn request theo token bucket, mỗi (route, identity) một
+// bucket. Identity là principal đã xác thực (API key/JWT), chưa xác thực thì
+// là IP. Route là "METHOD /path" theo route của gin, ví dụ "POST /api/v1/items".
+type RateLimiter struct {
+	limits map[string]RateLimit
+	now    func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	limit  RateLimit
+}
+
+// NewRateLimiter trả về nil khi không có giới hạn nào; limiter nil cho mọi
+// request đi qua.
+func NewRateLimiter(limits map[string]RateLimit) *RateLimiter {
+	if len(limits) == 0 {
+		return nil
+	}
+	return &RateLimiter{limits: limits, now: time.Now, buckets: make(map[string]*bucket)}
+}
+
+// ParseRateLimits đọc danh sách dạng "POST /api/v1/items=10/m:5,*=20/s",
+// mỗi phần là route=N/đơn vị (s, m, h)[:burst]. Burst mặc định bằng N.
+func ParseRateLimits(s string) (map[string]RateLimit, error) {
+	limits := make(map[string]RateLimit)
+	for _, entry := range strings.Split(s, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		route, spec, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(route) == "" {
+			return nil, fmt.Errorf("invalid rate limit entry %q, want route=N/unit[:burst]", entry)
+		}
+		limit, err := parseRateLimit(strings.TrimSpace(spec))
+		if err != nil {
+			return nil, fmt.Errorf("invalid rate limit entry %q: %w", entry, err)
+		}
+		limits[strings.Join(strings.Fields(route), " ")] = limit
+	}
+	return limits, nil
+}
+
+func parseRateLimit(spec string) (RateLimit, error) {
+	spec, burstStr, hasBurst := strings.Cut(spec, ":")
+	countStr, unit, ok := strings.Cut(spec, "/")
+	if !ok {
+		return RateLimit{}, fmt.Errorf("missing unit")
+	}
+	count, err := strconv.ParseFloat(countStr, 64)
+	if err != nil || count <= 0 {
+		return RateLimit{}, fmt.Errorf("invalid count %q", countStr)
+	}
+	per := map[string]float64{"s": 1, "m": 60, "h": 3600}[unit]
+	if per == 0 {
+		return RateLimit{}, fmt.Errorf("invalid unit %q, want s, m or h", unit)
+	}
+
+	limit := RateLimit{Rate: count / per, Burst: int(math.Ceil(count))}
+	if hasBurst {
+		if limit.Burst, err = strconv.Atoi(burstStr); err != nil || limit.Burst < 1 {
+			return RateLimit{}, fmt.Errorf("invalid burst %q", burstStr)
+		}
+	}
+	return limit, nil
+}
+
+// Middleware trả 429 kèm Retry-After khi identity dùng hết token của route.
+// Đặt sau RequireAuth/OptionalAuth để đếm theo principal thay vì IP.
+func (l *RateLimiter) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if l == nil {
+			c.Next()
+			return
+		}
+		route := c.Request.Method + " " + c.FullPath()
+		limit, ok := l.limits[route]
+		if !ok {
+			if limit, ok = l.limits[DefaultRoute]; !ok {
+				c.Next()
+				return
+			}
+			route = DefaultRoute
+		}
+
+		if wait, ok := l.allow(route+"|"+identity(c), limit); !ok {
+			c.Header("Retry-After", retryAfter(wait))
+			respondError(c, fmt.Errorf("%w: retry in %s", domain.ErrRateLimited, wait.Round(time.Millisecond)))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// allow lấy một token của bucket key; hết token thì trả về thời gian cần chờ.
+func (l *RateLimiter) allow(key string, limit RateLimit) (time.Duration, bool) {
+	now := l.now()
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if now.Sub(l.lastSweep) >= rateLimitSweep {
+		l.sweep(now)
+	}
+
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Burst), last: now, limit: limit}
+		l.buckets[key] = b
+	}
+	b.refill(now)
+	if b.tokens < 1 {
+		return time.Duration((1 - b.tokens) / limit.Rate * float64(time.Second)), false
+	}
+	b.tokens--
+	return 0, true
+}
+
+func (b *bucket) refill(now time.Time) {
+	b.tokens = math.Min(float64(b.limit.Burst), b.tokens+now.Sub(b.last).Seconds()*b.limit.Rate)
+	b.last = now
+}
+
+// sweep bỏ bucket đã nạp đầy lại: xoá đi cũng không thay đổi kết quả.
+func (l *RateLimiter) sweep(now time.Time) {
+	for key, b := range l.buckets {
+		b.refill(now)
+		if b.tokens >= float64(b.limit.Burst) {
+			delete(l.buckets, key)
+		}
+	}
+	l.lastSweep = now
+}
+
+// identity là key dùng cho rate limit và giới hạn kết nối: principal (kèm
+// tenant vì ID chỉ duy nhất trong tenant) nếu đã xác thực, không thì IP.
+func identity(c *gin.Context) string {
+	if p := auth.FromContext(c.Request.Context()); p != nil {
+		return "principal:" + p.TenantID + "/" + p.ID
+	}
+	return "ip:" + c.ClientIP()
+}
+
+// retryAfter làm tròn lên theo giây như header Retry-After yêu cầu.
+func retryAfter(wait time.Duration) string {
+	return strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds()))))
+}
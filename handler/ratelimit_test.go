@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestParseRateLimits(t *testing.T) {
+	limits, err := ParseRateLimits("POST /api/v1/items=10/m:3, *=20/s")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := limits["POST /api/v1/items"]; got.Burst != 3 || got.Rate != 10.0/60 {
+		t.Fatalf("POST items = %+v", got)
+	}
+	if got := limits[DefaultRoute]; got.Burst != 20 || got.Rate != 20 {
+		t.Fatalf("default = %+v", got)
+	}
+
+	for _, bad := range []string{"POST /x", "*=10", "*=0/s", "*=1/d", "*=1/s:0"} {
+		if _, err := ParseRateLimits(bad); err == nil {
+			t.Fatalf("%q: expected error", bad)
+		}
+	}
+}
+
+func TestRateLimiterMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	limiter := NewRateLimiter(map[string]RateLimit{
+		"POST /items": {Rate: 1, Burst: 2},
+		DefaultRoute:  {Rate: 100, Burst: 100},
+	})
+	now := time.Unix(1000, 0)
+	limiter.now = func() time.Time { return now }
+
+	r := gin.New()
+	r.Use(limiter.Middleware())
+	r.POST("/items", func(c *gin.Context) { c.Status(http.StatusCreated) })
+	r.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+	do := func(method, ip string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/items", nil)
+		req.RemoteAddr = ip + ":1234"
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w
+	}
+
+	for i := 0; i < 2; i++ {
+		if w := do("POST", "10.0.0.1"); w.Code != http.StatusCreated {
+			t.Fatalf("burst request %d: status %d", i, w.Code)
+		}
+	}
+	w := do("POST", "10.0.0.1")
+	if w.Code != http.StatusTooManyRequests || w.Header().Get("Retry-After") != "1" {
+		t.Fatalf("over limit: status %d, Retry-After %q", w.Code, w.Header().Get("Retry-After"))
+	}
+
+	// bucket riêng theo route và theo identity
+	if w := do("GET", "10.0.0.1"); w.Code != http.StatusOK {
+		t.Fatalf("other route: status %d", w.Code)
+	}
+	if w := do("POST", "10.0.0.2"); w.Code != http.StatusCreated {
+		t.Fatalf("other ip: status %d", w.Code)
+	}
+
+	now = now.Add(time.Second)
+	if w := do("POST", "10.0.0.1"); w.Code != http.StatusCreated {
+		t.Fatalf("after refill: status %d", w.Code)
+	}
+}
+
+func TestRateLimiterIgnoresSpoofedForwardedFor(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	newRouter := func(trusted []string) *gin.Engine {
+		limiter := NewRateLimiter(map[string]RateLimit{DefaultRoute: {Rate: 1, Burst: 1}})
+		now := time.Unix(1000, 0)
+		limiter.now = func() time.Time { return now }
+		r := gin.New()
+		if err := r.SetTrustedProxies(trusted); err != nil {
+			t.Fatal(err)
+		}
+		r.Use(limiter.Middleware())
+		r.GET("/items", func(c *gin.Context) { c.Status(http.StatusOK) })
+		return r
+	}
+	do := func(r *gin.Engine, remote, forwarded string) int {
+		req := httptest.NewRequest(http.MethodGet, "/items", nil)
+		req.RemoteAddr = remote + ":1234"
+		req.Header.Set("X-Forwarded-For", forwarded)
+		w := httptest.NewRecorder()
+		r.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// mặc định không tin proxy nào: đổi X-Forwarded-For không thoát được limit
+	r := newRouter(nil)
+	if code := do(r, "203.0.113.7", "1.1.1.1"); code != http.StatusOK {
+		t.Fatalf("first request: status %d", code)
+	}
+	if code := do(r, "203.0.113.7", "2.2.2.2"); code != http.StatusTooManyRequests {
+		t.Fatalf("spoofed X-Forwarded-For: status %d, want 429", code)
+	}
+
+	// sau proxy tin cậy thì mỗi client thật có bucket riêng
+	r = newRouter([]string{"10.0.0.0/8"})
+	if code := do(r, "10.0.0.1", "1.1.1.1"); code != http.StatusOK {
+		t.Fatalf("client 1 via proxy: status %d", code)
+	}
+	if code := do(r, "10.0.0.1", "2.2.2.2"); code != http.StatusOK {
+		t.Fatalf("client 2 via proxy: status %d", code)
+	}
+	if code := do(r, "10.0.0.1", "1.1.1.1"); code != http.StatusTooManyRequests {
+		t.Fatalf("client 1 again via proxy: status %d, want 429", code)
+	}
+}
@@ -0,0 +1,105 @@
+package handler
+
+import (
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+
+	"github.com/JIeeiroSst/hub/auth"
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+const defaultTenantHeader = "X-Tenant-ID"
+
+// TenantResolver xác định tenant của request. Tenant trong credential (claim
+// JWT, API key) là nguồn chính; header và subdomain chỉ được dùng để chọn
+// tenant khi auth tắt, còn khi có principal thì phải khớp với credential.
+type TenantResolver struct {
+	// Header chứa tenant, mặc định X-Tenant-ID.
+	Header string
+	// BaseDomain bật resolve theo subdomain: acme.<BaseDomain> → "acme".
+	BaseDomain string
+	// Required từ chối request không xác định được tenant.
+	Required bool
+}
+
+// Middleware gắn tenant vào context của request. Resolver nil chỉ dùng
+// tenant của principal.
+func (t *TenantResolver) Middleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		tenant, err := t.resolve(c.Request, auth.FromContext(c.Request.Context()))
+		if err != nil {
+			respondError(c, err)
+			c.Abort()
+			return
+		}
+		c.Request = c.Request.WithContext(auth.WithTenant(c.Request.Context(), tenant))
+		c.Next()
+	}
+}
+
+func (t *TenantResolver) resolve(r *http.Request, p *auth.Principal) (string, error) {
+	if t == nil {
+		return p.Scope().TenantID, nil
+	}
+
+	requested, err := t.requested(r)
+	if err != nil {
+		return "", err
+	}
+
+	tenant := requested
+	switch {
+	case p != nil && p.TenantID != "":
+		if requested != "" && requested != p.TenantID {
+			return "", fmt.Errorf("%w: credential is not valid for tenant %s", domain.ErrForbidden, requested)
+		}
+		tenant = p.TenantID
+	case p != nil && requested != "":
+		return "", fmt.Errorf("%w: principal %s does not belong to tenant %s", domain.ErrForbidden, p.ID, requested)
+	}
+
+	if tenant == "" && t.Required {
+		return "", fmt.Errorf("%w: tenant is required", domain.ErrForbidden)
+	}
+	return tenant, nil
+}
+
+// requested đọc tenant từ header và subdomain; nếu có cả hai thì phải giống nhau.
+func (t *TenantResolver) requested(r *http.Request) (string, error) {
+	header := t.Header
+	if header == "" {
+		header = defaultTenantHeader
+	}
+	fromHeader := strings.TrimSpace(r.Header.Get(header))
+	fromHost := t.subdomain(r.Host)
+
+	if fromHeader != "" && fromHost != "" && fromHeader != fromHost {
+		return "", fmt.Errorf("%w: tenant header %s does not match host %s", domain.ErrValidation, fromHeader, r.Host)
+	}
+	tenant := fromHeader
+	if tenant == "" {
+		tenant = fromHost
+	}
+	if tenant != "" && !domain.ValidTenantID(tenant) {
+		return "", fmt.Errorf("%w: invalid tenant id %q", domain.ErrValidation, tenant)
+	}
+	return tenant, nil
+}
+
+func (t *TenantResolver) subdomain(host string) string {
+	if t.BaseDomain == "" {
+		return ""
+	}
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	sub, ok := strings.CutSuffix(strings.ToLower(host), "."+strings.ToLower(t.BaseDomain))
+	if !ok || strings.Contains(sub, ".") {
+		return ""
+	}
+	return sub
+}
@@ -0,0 +1,65 @@
+package handler
+
+import (
+	"errors"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/JIeeiroSst/hub/auth"
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+func TestTenantResolver(t *testing.T) {
+	resolver := &TenantResolver{BaseDomain: "example.com"}
+	acmeKey := &auth.Principal{ID: "alice", TenantID: "acme"}
+	noTenant := &auth.Principal{ID: "bob"}
+
+	cases := []struct {
+		name    string
+		host    string
+		header  string
+		p       *auth.Principal
+		want    string
+		wantErr error
+	}{
+		{"auth disabled, no tenant", "api.local", "", nil, "", nil},
+		{"auth disabled, header", "api.local", "acme", nil, "acme", nil},
+		{"auth disabled, subdomain", "globex.example.com:8080", "", nil, "globex", nil},
+		{"header and subdomain disagree", "globex.example.com", "acme", nil, "", domain.ErrValidation},
+		{"invalid tenant id", "api.local", "../acme", nil, "", domain.ErrValidation},
+		{"credential tenant", "api.local", "", acmeKey, "acme", nil},
+		{"credential tenant, matching header", "acme.example.com", "acme", acmeKey, "acme", nil},
+		{"credential tenant, other header", "api.local", "globex", acmeKey, "", domain.ErrForbidden},
+		{"credential tenant, other subdomain", "globex.example.com", "", acmeKey, "", domain.ErrForbidden},
+		{"principal without tenant picks one", "api.local", "acme", noTenant, "", domain.ErrForbidden},
+		{"nested subdomain ignored", "a.acme.example.com", "", nil, "", nil},
+	}
+	for _, tc := range cases {
+		r := httptest.NewRequest("GET", "/api/v1/items", nil)
+		r.Host = tc.host
+		if tc.header != "" {
+			r.Header.Set("X-Tenant-ID", tc.header)
+		}
+		got, err := resolver.resolve(r, tc.p)
+		if tc.wantErr != nil {
+			if !errors.Is(err, tc.wantErr) {
+				t.Fatalf("%s: err = %v, want %v", tc.name, err, tc.wantErr)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("%s: got %q, %v; want %q", tc.name, got, err, tc.want)
+		}
+	}
+
+	required := &TenantResolver{Required: true}
+	if _, err := required.resolve(httptest.NewRequest("GET", "/", nil), noTenant); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("required: err = %v, want ErrForbidden", err)
+	}
+
+	// resolver nil chỉ dùng tenant của principal
+	var none *TenantResolver
+	if got, _ := none.resolve(httptest.NewRequest("GET", "/", nil), acmeKey); got != "acme" {
+		t.Fatalf("nil resolver: got %q, want acme", got)
+	}
+}
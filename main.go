@@ -2,11 +2,22 @@
 package main
 
 import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
 	"log"
+	"net/http"
 	"os"
+	"os/signal"
+	"strconv"
+	"syscall"
 
 	"github.com/gin-gonic/gin"
+	"github.com/redis/go-redis/v9"
 
+	"github.com/JIeeiroSst/hub/auth"
+	"github.com/JIeeiroSst/hub/config"
 	"github.com/JIeeiroSst/hub/handler"
 	"github.com/JIeeiroSst/hub/repository"
 	"github.com/JIeeiroSst/hub/service"
@@ -14,65 +25,207 @@ import (
 )
 
 func main() {
-	dbType := repository.DBType(getEnv("DB_TYPE", "postgres"))
+	// ưu tiên: flag > biến môi trường > file (--config/CONFIG_FILE) > mặc định
+	cfg, args, err := config.Load(os.Args[1:])
+	if errors.Is(err, flag.ErrHelp) {
+		return
+	}
+	if err != nil {
+		log.Fatalf("Config: %v", err)
+	}
+	dbType := cfg.Database.Type
 
-	cfg := repository.DBConfig{
-		Type: dbType,
-		DSN: getEnv("DB_DSN", "host=localhost user=postgres password=postgres dbname=items_db port=5432 sslmode=disable"),
+	// ./server [flags] config: in cấu hình đã nạp (secret bị che) rồi thoát
+	if len(args) > 0 && args[0] == "config" {
+		fmt.Print(cfg.Redacted())
+		return
+	}
 
-		MongoURI:      getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		MongoDBName:   getEnv("MONGO_DB", "items_db"),
-		MongoCollName: getEnv("MONGO_COLL", "items"),
+	// ./server [flags] migrate up|down [n]|status
+	if len(args) > 0 && args[0] == "migrate" {
+		if err := runMigrate(cfg.Database.Repository(), args[1:]); err != nil {
+			log.Fatalf("Migrate [%s]: %v", dbType, err)
+		}
+		return
 	}
+	log.Printf("⚙️  Config:\n%s", cfg.Redacted())
 
-	repo, err := repository.NewRepository(cfg)
+	repo, err := repository.NewRepository(cfg.Database.Repository())
 	if err != nil {
 		log.Fatalf("Failed to initialize repository [%s]: %v", dbType, err)
 	}
 	log.Printf("✅ Database strategy initialized: %s", dbType)
 
-	hub := ws.NewHub()
-	go hub.Run() 
+	// ctx bị huỷ khi nhận SIGINT/SIGTERM; relay và change feed dừng theo
+	// background (huỷ sau khi HTTP đã drain xong)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+	background, cancelBackground := context.WithCancel(context.Background())
+	defer cancelBackground()
+
+	hubOpts := cfg.Hub.HubOptions()
+	var backplane ws.Backplane
+	// redis.addr bật backplane để event tới client ở mọi replica
+	if addr := cfg.Redis.Addr; addr != "" {
+		rdb := redis.NewClient(&redis.Options{Addr: addr, Password: cfg.Redis.Password})
+		backplane = ws.NewRedisBackplane(rdb, cfg.Redis.Channel)
+		hubOpts = append(hubOpts, ws.WithBackplane(backplane))
+		log.Printf("📡 Hub backplane: redis %s", addr)
+
+		// hàng đợi broadcast đầy thì ghi tạm event vào Redis list của replica này
+		if cfg.Hub.BroadcastOverflow == ws.OverflowSpill {
+			hubOpts = append(hubOpts, ws.WithSpill(ws.NewRedisSpill(rdb, cfg.Hub.SpillKey)))
+			log.Printf("📦 Hub broadcast spill: redis list %s", cfg.Hub.SpillKey)
+		}
+	}
+
+	hub := ws.NewHub(hubOpts...)
+	go hub.Run()
+
+	// event ghi vào outbox cùng transaction với item, relay đẩy ra hub.
+	// change_feed: event lấy từ chính DB (trigger/change stream/polling),
+	// outbox khi đó chỉ còn được đánh dấu đã giao để khỏi gửi trùng.
+	relayHub := hub
+	var feedRelay *service.ChangeFeedRelay
+	if cfg.ChangeFeed {
+		feed, ok := repo.(repository.ChangeFeed)
+		if !ok {
+			log.Fatalf("Change feed is not supported by %s", dbType)
+		}
+		feedRelay = service.NewChangeFeedRelay(feed, hub)
+		if err := feedRelay.Start(background); err != nil {
+			log.Fatalf("Failed to start change feed [%s]: %v", dbType, err)
+		}
+		relayHub = nil
+		log.Printf("🔔 Change feed enabled: %s", dbType)
+	}
+
+	relay := service.NewOutboxRelay(repo, relayHub)
+	relayDone := make(chan struct{})
+	go func() {
+		defer close(relayDone)
+		relay.Run(background)
+	}()
+
+	svc := service.NewItemService(repo, relay)
+	authn, err := loadAuthenticator(cfg.Auth)
+	if err != nil {
+		log.Fatalf("Failed to initialize auth: %v", err)
+	}
+	if authn == nil {
+		log.Printf("⚠️  Auth disabled: set auth.api_keys, auth.jwt_secret or auth.jwks_file")
+	}
+
+	// tenant lấy từ credential; header/subdomain chỉ để chọn tenant khi auth tắt
+	tenants := cfg.Tenant.Resolver()
+	cors := cfg.CORS.Handler()
+
+	h := handler.NewItemHandler(svc, hub, authn, tenants, cors)
 
-	svc := service.NewItemService(repo, hub)
-	h := handler.NewItemHandler(svc, hub)
+	// đã kiểm tra trong config.Validate
+	limits, _ := handler.ParseRateLimits(cfg.RateLimits)
+	limiter := handler.NewRateLimiter(limits)
 
 	r := gin.Default()
+	// không đặt thì gin tin X-Forwarded-For từ mọi nơi, client tự đổi được
+	// IP dùng cho rate limit; nil là không tin proxy nào
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("Invalid trusted proxies: %v", err)
+	}
 
-	r.Use(func(c *gin.Context) {
-		c.Header("Access-Control-Allow-Origin", "*")
-		c.Header("Access-Control-Allow-Methods", "GET, POST, PUT, DELETE, OPTIONS")
-		c.Header("Access-Control-Allow-Headers", "Content-Type, Authorization")
-		if c.Request.Method == "OPTIONS" {
-			c.AbortWithStatus(204)
-			return
-		}
-		c.Next()
-	})
+	r.Use(cors.Middleware())
+
+	// health để public cho probe của load balancer/k8s
+	r.GET("/api/v1/health", h.Health)
 
-	v1 := r.Group("/api/v1")
+	v1 := r.Group("/api/v1", handler.RequireAuth(authn), limiter.Middleware(), tenants.Middleware())
 	{
-		v1.POST("/items", h.Create)     // Tạo item → tự broadcast real-time
-		v1.GET("/items", h.List)        // Lấy list, sort created_at DESC
-		v1.GET("/items/:id", h.GetByID) // Lấy theo ID
-		v1.GET("/health", h.Health)     // Health check
+		v1.POST("/items", h.Create)       // Tạo item → tự broadcast real-time
+		v1.GET("/items", h.List)          // Lấy list, sort created_at DESC
+		v1.GET("/items/:id", h.GetByID)   // Lấy theo ID
+		v1.PUT("/items/:id", h.Replace)   // Ghi đè item, chưa có thì tạo → broadcast ITEM_UPDATED/ITEM_CREATED
+		v1.PATCH("/items/:id", h.Update)  // Cập nhật một phần → broadcast ITEM_UPDATED
+		v1.DELETE("/items/:id", h.Delete) // Xoá item → broadcast ITEM_DELETED
+		v1.GET("/events", h.Events)       // SSE: cùng event với /ws, resume bằng Last-Event-ID
 	}
 
-	r.GET("/ws", h.WebSocket)
+	// /ws: credential qua header/?access_token= lúc upgrade, hoặc message auth đầu tiên
+	r.GET("/ws", handler.OptionalAuth(authn), limiter.Middleware(), h.WebSocket)
 
-	port := getEnv("PORT", "8080")
+	port := strconv.Itoa(cfg.Server.Port)
 	log.Printf("🚀 Server running on :%s", port)
 	log.Printf("📡 WebSocket endpoint: ws://localhost:%s/ws", port)
+	log.Printf("📡 SSE endpoint: GET http://localhost:%s/api/v1/events", port)
 	log.Printf("📋 List API: GET http://localhost:%s/api/v1/items", port)
 
-	if err := r.Run(":" + port); err != nil {
+	srv := &http.Server{
+		Addr:              ":" + port,
+		Handler:           r,
+		ReadHeaderTimeout: cfg.Server.ReadHeaderTimeout,
+		ReadTimeout:       cfg.Server.ReadTimeout,
+		WriteTimeout:      cfg.Server.WriteTimeout,
+		IdleTimeout:       cfg.Server.IdleTimeout,
+		MaxHeaderBytes:    1 << 20,
+	}
+	serveErr := make(chan error, 1)
+	go func() { serveErr <- srv.ListenAndServe() }()
+
+	select {
+	case err := <-serveErr:
 		log.Fatalf("Server error: %v", err)
+	case <-ctx.Done():
+	}
+	stop() // tín hiệu thứ hai thì thoát ngay theo mặc định
+	log.Printf("🛑 Shutting down (timeout %s)...", cfg.Server.ShutdownTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), cfg.Server.ShutdownTimeout)
+	defer cancel()
+
+	// Shutdown đóng listener ngay rồi chờ request đang chạy; SSE và WebSocket
+	// chỉ kết thúc khi hub đóng client nên hub.Shutdown chạy song song.
+	httpDone := make(chan error, 1)
+	go func() { httpDone <- srv.Shutdown(shutdownCtx) }()
+	if err := hub.Shutdown(shutdownCtx); err != nil {
+		log.Printf("⚠️  Hub shutdown: %v", err)
+	}
+	if err := <-httpDone; err != nil {
+		log.Printf("⚠️  HTTP shutdown: %v", err)
+	}
+
+	// không còn request nào ghi outbox nữa: dừng relay, change feed rồi mới đóng DB.
+	// Event chưa giao vẫn nằm trong outbox và được gửi ở lần chạy sau.
+	cancelBackground()
+	waitDone(shutdownCtx, relayDone)
+	if feedRelay != nil {
+		waitDone(shutdownCtx, feedRelay.Done())
 	}
+	if backplane != nil {
+		if err := backplane.Close(); err != nil {
+			log.Printf("⚠️  Backplane close: %v", err)
+		}
+	}
+	if err := repo.Close(shutdownCtx); err != nil {
+		log.Printf("⚠️  Repository close: %v", err)
+	}
+	log.Printf("👋 Server stopped")
 }
 
-func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+func waitDone(ctx context.Context, done <-chan struct{}) {
+	select {
+	case <-done:
+	case <-ctx.Done():
+	}
+}
+
+// loadAuthenticator trả về nil khi không cấu hình cách xác thực nào.
+func loadAuthenticator(cfg config.AuthConfig) (auth.Authenticator, error) {
+	keys, err := auth.ParseAPIKeys(cfg.APIKeys)
+	if err != nil {
+		return nil, err
+	}
+	jwtAuth, err := auth.NewJWTAuthenticator(cfg.JWT())
+	if err != nil {
+		return nil, err
 	}
-	return fallback
+	return auth.Chain(auth.NewAPIKeyAuthenticator(keys), jwtAuth), nil
 }
@@ -0,0 +1,63 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+const (
+	changeFeedBuffer    = 64
+	changeFeedBatchSize = 100
+	// changeFeedRetry là thời gian chờ trước khi kết nối lại khi feed bị lỗi.
+	changeFeedRetry = 2 * time.Second
+	// changeFeedPollInterval chỉ dùng cho backend phải polling (MySQL).
+	changeFeedPollInterval = time.Second
+)
+
+func (r *GormStrategy) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	if r.dialect.Watch == nil {
+		return nil, fmt.Errorf("%s change feed: %w", r.dialect.Name, errors.ErrUnsupported)
+	}
+
+	out := make(chan domain.ChangeEvent, changeFeedBuffer)
+	go func() {
+		defer close(out)
+		r.dialect.Watch(ctx, r, out)
+	}()
+	return out, nil
+}
+
+func newChangeEvent(eventType string, payload interface{}) (domain.ChangeEvent, error) {
+	data, err := json.Marshal(payload)
+	if err != nil {
+		return domain.ChangeEvent{}, fmt.Errorf("change feed payload error: %w", err)
+	}
+	return domain.ChangeEvent{Type: eventType, Payload: data}, nil
+}
+
+// emit gửi ev vào out, trả về false nếu ctx đã bị huỷ.
+func emit(ctx context.Context, out chan<- domain.ChangeEvent, ev domain.ChangeEvent) bool {
+	select {
+	case out <- ev:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// sleepCtx chờ d hoặc tới khi ctx bị huỷ, trả về false nếu ctx đã bị huỷ.
+func sleepCtx(ctx context.Context, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
@@ -0,0 +1,763 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"github.com/google/uuid"
+)
+
+// repoFactory trả về một ItemRepository rỗng cho mỗi subtest.
+type repoFactory func(t *testing.T) ItemRepository
+
+// runConformance kiểm tra hợp đồng của ItemRepository; mọi strategy phải pass.
+func runConformance(t *testing.T, newRepo repoFactory) {
+	t.Run("CreateAndGet", func(t *testing.T) { testCreateAndGet(t, newRepo(t)) })
+	t.Run("GetNotFound", func(t *testing.T) { testGetNotFound(t, newRepo(t)) })
+	t.Run("Update", func(t *testing.T) { testUpdate(t, newRepo(t)) })
+	t.Run("Delete", func(t *testing.T) { testDelete(t, newRepo(t)) })
+	t.Run("Upsert", func(t *testing.T) { testUpsert(t, newRepo(t)) })
+	t.Run("ListOrdering", func(t *testing.T) { testListOrdering(t, newRepo(t)) })
+	t.Run("ListPaging", func(t *testing.T) { testListPaging(t, newRepo(t)) })
+	t.Run("ListCursor", func(t *testing.T) { testListCursor(t, newRepo(t)) })
+	t.Run("ListFilters", func(t *testing.T) { testListFilters(t, newRepo(t)) })
+	t.Run("ConcurrentCreate", func(t *testing.T) { testConcurrentCreate(t, newRepo(t)) })
+	t.Run("Outbox", func(t *testing.T) { testOutbox(t, newRepo(t)) })
+	t.Run("OutboxClaim", func(t *testing.T) { testOutboxClaim(t, newRepo(t)) })
+	t.Run("OutboxFailedWrite", func(t *testing.T) { testOutboxFailedWrite(t, newRepo(t)) })
+	t.Run("Scope", func(t *testing.T) { testScope(t, newRepo(t)) })
+	t.Run("TenantIsolation", func(t *testing.T) { testTenantIsolation(t, newRepo(t)) })
+	t.Run("ChangeFeed", func(t *testing.T) { testChangeFeed(t, newRepo(t)) })
+}
+
+func seedItems(t *testing.T, repo ItemRepository, n int) []*domain.Item {
+	t.Helper()
+	items := make([]*domain.Item, 0, n)
+	for i := 0; i < n; i++ {
+		// name lặp lại để kiểm tra tie-break theo id
+		item, err := repo.Create(context.Background(), &domain.Item{
+			Name:    fmt.Sprintf("item-%02d", i%4),
+			Content: fmt.Sprintf("content %d", i),
+		})
+		if err != nil {
+			t.Fatalf("create #%d: %v", i, err)
+		}
+		items = append(items, item)
+	}
+	return items
+}
+
+func listAll(t *testing.T, repo ItemRepository, params domain.ListParams) []*domain.Item {
+	t.Helper()
+	params.PageSize = 1000
+	result, err := repo.List(context.Background(), domain.Scope{}, params)
+	if err != nil {
+		t.Fatalf("list %+v: %v", params, err)
+	}
+	return result.Items
+}
+
+func ids(items []*domain.Item) []string {
+	out := make([]string, len(items))
+	for i, item := range items {
+		out[i] = item.ID
+	}
+	return out
+}
+
+func assertSameIDs(t *testing.T, label string, got, want []*domain.Item) {
+	t.Helper()
+	g, w := ids(got), ids(want)
+	if len(g) != len(w) {
+		t.Fatalf("%s: got %d items, want %d", label, len(g), len(w))
+	}
+	for i := range g {
+		if g[i] != w[i] {
+			t.Fatalf("%s: item %d = %s, want %s", label, i, g[i], w[i])
+		}
+	}
+}
+
+func testCreateAndGet(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+
+	created, err := repo.Create(ctx, &domain.Item{Name: "hello", Content: "world"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	if created.ID == "" {
+		t.Fatal("create: empty id")
+	}
+	if created.CreatedAt.IsZero() || created.UpdatedAt.IsZero() {
+		t.Fatalf("create: timestamps not set: %+v", created)
+	}
+
+	got, err := repo.GetByID(ctx, domain.Scope{}, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.ID != created.ID || got.Name != "hello" || got.Content != "world" {
+		t.Fatalf("get: got %+v, want %+v", got, created)
+	}
+}
+
+func testGetNotFound(t *testing.T, repo ItemRepository) {
+	_, err := repo.GetByID(context.Background(), domain.Scope{}, "00000000-0000-0000-0000-000000000000")
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func testUpdate(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	created, err := repo.Create(ctx, &domain.Item{Name: "before", Content: "keep"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// đọc lại để so sánh updated_at cùng độ chính xác của DB
+	before, err := repo.GetByID(ctx, domain.Scope{}, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	time.Sleep(10 * time.Millisecond)
+
+	name := "after"
+	updated, err := repo.Update(ctx, domain.Scope{}, created.ID, domain.ItemPatch{Name: &name})
+	if err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if updated.Name != "after" || updated.Content != "keep" {
+		t.Fatalf("update: got %+v", updated)
+	}
+	if !updated.UpdatedAt.After(before.UpdatedAt) {
+		t.Fatalf("update: updated_at not bumped: %v <= %v", updated.UpdatedAt, before.UpdatedAt)
+	}
+
+	got, err := repo.GetByID(ctx, domain.Scope{}, created.ID)
+	if err != nil {
+		t.Fatalf("get: %v", err)
+	}
+	if got.Name != "after" {
+		t.Fatalf("get after update: name = %q", got.Name)
+	}
+
+	_, err = repo.Update(ctx, domain.Scope{}, "00000000-0000-0000-0000-000000000000", domain.ItemPatch{Name: &name})
+	if !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("update missing: got %v, want ErrNotFound", err)
+	}
+}
+
+func testDelete(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	created, err := repo.Create(ctx, &domain.Item{Name: "doomed"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	if err := repo.Delete(ctx, domain.Scope{}, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	if _, err := repo.GetByID(ctx, domain.Scope{}, created.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get deleted: got %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, domain.Scope{}, created.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("delete twice: got %v, want ErrNotFound", err)
+	}
+}
+
+func testUpsert(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	alice := domain.Scope{OwnerID: "alice", TenantID: "acme"}
+	id := uuid.NewString()
+
+	first, created, err := repo.Upsert(ctx, alice, &domain.Item{ID: id, Name: "first", Content: "v1", OwnerID: "alice", TenantID: "acme"})
+	if err != nil || !created || first.ID != id || first.Name != "first" || first.OwnerID != "alice" {
+		t.Fatalf("upsert new: got %+v, created %v, err %v", first, created, err)
+	}
+	time.Sleep(10 * time.Millisecond)
+	second, created, err := repo.Upsert(ctx, alice, &domain.Item{ID: id, Name: "second", Content: "v2", OwnerID: "alice", TenantID: "acme"})
+	if err != nil || created || second.Name != "second" || second.Content != "v2" {
+		t.Fatalf("upsert existing: got %+v, created %v, err %v", second, created, err)
+	}
+	if !second.CreatedAt.Equal(first.CreatedAt) || !second.UpdatedAt.After(first.UpdatedAt) {
+		t.Fatalf("timestamps: created %v -> %v, updated %v -> %v", first.CreatedAt, second.CreatedAt, first.UpdatedAt, second.UpdatedAt)
+	}
+	if got, err := repo.GetByID(ctx, alice, id); err != nil || got.Name != "second" {
+		t.Fatalf("get after upsert: got %+v, %v", got, err)
+	}
+
+	// ghi đè item của người khác: cùng tenant thì Forbidden, khác tenant thì id đã bị dùng
+	bob := domain.Scope{OwnerID: "bob", TenantID: "acme"}
+	if _, _, err := repo.Upsert(ctx, bob, &domain.Item{ID: id, Name: "bob", OwnerID: "bob", TenantID: "acme"}); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("upsert other member's item: got %v, want ErrForbidden", err)
+	}
+	globex := domain.Scope{OwnerID: "alice", TenantID: "globex"}
+	if _, _, err := repo.Upsert(ctx, globex, &domain.Item{ID: id, Name: "globex", OwnerID: "alice", TenantID: "globex"}); !errors.Is(err, domain.ErrConflict) {
+		t.Fatalf("upsert item of other tenant: got %v, want ErrConflict", err)
+	}
+	admin := domain.Scope{OwnerID: "carol", TenantID: "acme", Admin: true}
+	if item, _, err := repo.Upsert(ctx, admin, &domain.Item{ID: id, Name: "moderated", OwnerID: "carol", TenantID: "acme"}); err != nil || item.OwnerID != "alice" {
+		t.Fatalf("upsert as admin: got %+v, %v", item, err)
+	}
+
+	pending, err := repo.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("fetch pending: %v", err)
+	}
+	var types []string
+	for _, ev := range pending {
+		types = append(types, ev.Type)
+	}
+	want := []string{domain.EventItemCreated, domain.EventItemUpdated, domain.EventItemUpdated}
+	if fmt.Sprint(types) != fmt.Sprint(want) {
+		t.Fatalf("outbox events = %v, want %v", types, want)
+	}
+}
+
+func testListOrdering(t *testing.T, repo ItemRepository) {
+	seedItems(t, repo, 12)
+
+	for field := range allowedSortFields {
+		for _, dir := range []string{"asc", "desc"} {
+			items := listAll(t, repo, domain.ListParams{SortBy: field, SortDir: dir})
+			if len(items) != 12 {
+				t.Fatalf("%s %s: got %d items, want 12", field, dir, len(items))
+			}
+			sorted := sort.SliceIsSorted(items, func(i, j int) bool {
+				c := compareItems(items[i], items[j], field)
+				if dir == "asc" {
+					return c < 0
+				}
+				return c > 0
+			})
+			if !sorted {
+				t.Fatalf("%s %s: items not sorted by (%s, id)", field, dir, field)
+			}
+		}
+	}
+
+	// sort_by không hợp lệ rơi về created_at
+	fallback := listAll(t, repo, domain.ListParams{SortBy: "content; DROP TABLE items"})
+	assertSameIDs(t, "invalid sort_by", fallback, listAll(t, repo, domain.ListParams{SortBy: "created_at"}))
+}
+
+func testListPaging(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	seedItems(t, repo, 7)
+	all := listAll(t, repo, domain.ListParams{})
+
+	var paged []*domain.Item
+	for page := 1; page <= 3; page++ {
+		result, err := repo.List(ctx, domain.Scope{}, domain.ListParams{Page: page, PageSize: 3})
+		if err != nil {
+			t.Fatalf("page %d: %v", page, err)
+		}
+		if result.Total != 7 || result.TotalPages != 3 || result.Page != page || result.PageSize != 3 {
+			t.Fatalf("page %d: unexpected meta %+v", page, result)
+		}
+		paged = append(paged, result.Items...)
+	}
+	assertSameIDs(t, "page walk", paged, all)
+
+	beyond, err := repo.List(ctx, domain.Scope{}, domain.ListParams{Page: 10, PageSize: 3})
+	if err != nil {
+		t.Fatalf("page beyond: %v", err)
+	}
+	if len(beyond.Items) != 0 || beyond.Total != 7 {
+		t.Fatalf("page beyond: got %d items, total %d", len(beyond.Items), beyond.Total)
+	}
+
+	defaults, err := repo.List(ctx, domain.Scope{}, domain.ListParams{Page: -1, PageSize: 0})
+	if err != nil {
+		t.Fatalf("defaults: %v", err)
+	}
+	if defaults.Page != 1 || defaults.PageSize != 20 || len(defaults.Items) != 7 {
+		t.Fatalf("defaults: unexpected meta %+v", defaults)
+	}
+}
+
+func testListCursor(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	seedItems(t, repo, 10)
+
+	for _, field := range []string{"created_at", "name", "id"} {
+		for _, dir := range []string{"asc", "desc"} {
+			params := domain.ListParams{SortBy: field, SortDir: dir, PageSize: 3}
+			all := listAll(t, repo, params)
+
+			var forward []*domain.Item
+			var last *domain.ListResult
+			for {
+				result, err := repo.List(ctx, domain.Scope{}, params)
+				if err != nil {
+					t.Fatalf("%s %s forward: %v", field, dir, err)
+				}
+				forward = append(forward, result.Items...)
+				last = result
+				if result.NextCursor == "" {
+					break
+				}
+				params.Cursor = result.NextCursor
+			}
+			assertSameIDs(t, fmt.Sprintf("%s %s forward", field, dir), forward, all)
+
+			backward := last.Items
+			params.Cursor = last.PrevCursor
+			for params.Cursor != "" {
+				result, err := repo.List(ctx, domain.Scope{}, params)
+				if err != nil {
+					t.Fatalf("%s %s backward: %v", field, dir, err)
+				}
+				backward = append(append([]*domain.Item{}, result.Items...), backward...)
+				params.Cursor = result.PrevCursor
+			}
+			assertSameIDs(t, fmt.Sprintf("%s %s backward", field, dir), backward, all)
+		}
+	}
+
+	if _, err := repo.List(ctx, domain.Scope{}, domain.ListParams{Cursor: "not-a-cursor"}); !errors.Is(err, domain.ErrValidation) {
+		t.Fatalf("invalid cursor: got %v, want ErrValidation", err)
+	}
+}
+
+func testListFilters(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+
+	first, err := repo.Create(ctx, &domain.Item{Name: "zebra crossing", Content: "striped"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	time.Sleep(50 * time.Millisecond)
+	mark := time.Now()
+	time.Sleep(50 * time.Millisecond)
+	second, err := repo.Create(ctx, &domain.Item{Name: "tall animal", Content: "giraffe neck"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		params domain.ListParams
+		want   []*domain.Item
+	}{
+		{"q name", domain.ListParams{Q: "zebra"}, []*domain.Item{first}},
+		{"q content", domain.ListParams{Q: "giraffe"}, []*domain.Item{second}},
+		{"q no match", domain.ListParams{Q: "elephant"}, nil},
+		{"created_after", domain.ListParams{CreatedAfter: mark}, []*domain.Item{second}},
+		{"created_before", domain.ListParams{CreatedBefore: mark}, []*domain.Item{first}},
+		{"updated_after", domain.ListParams{UpdatedAfter: mark}, []*domain.Item{second}},
+	}
+	for _, tc := range cases {
+		result, err := repo.List(ctx, domain.Scope{}, tc.params)
+		if err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		if result.Total != int64(len(tc.want)) {
+			t.Fatalf("%s: total = %d, want %d", tc.name, result.Total, len(tc.want))
+		}
+		assertSameIDs(t, tc.name, result.Items, tc.want)
+	}
+}
+
+func testConcurrentCreate(t *testing.T, repo ItemRepository) {
+	const workers, perWorker = 8, 10
+	ctx := context.Background()
+
+	var wg sync.WaitGroup
+	errs := make(chan error, workers*perWorker)
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			for i := 0; i < perWorker; i++ {
+				if _, err := repo.Create(ctx, &domain.Item{Name: fmt.Sprintf("w%d-%d", w, i)}); err != nil {
+					errs <- err
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("concurrent create: %v", err)
+	}
+
+	items := listAll(t, repo, domain.ListParams{})
+	if len(items) != workers*perWorker {
+		t.Fatalf("got %d items, want %d", len(items), workers*perWorker)
+	}
+	seen := make(map[string]bool, len(items))
+	for _, item := range items {
+		if seen[item.ID] {
+			t.Fatalf("duplicate id %s", item.ID)
+		}
+		seen[item.ID] = true
+	}
+}
+
+func testOutbox(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	created, err := repo.Create(ctx, &domain.Item{Name: "tracked"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	name := "renamed"
+	if _, err := repo.Update(ctx, domain.Scope{}, created.ID, domain.ItemPatch{Name: &name}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	if err := repo.Delete(ctx, domain.Scope{}, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	pending, err := repo.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("fetch pending: %v", err)
+	}
+	wantTypes := []string{domain.EventItemCreated, domain.EventItemUpdated, domain.EventItemDeleted}
+	wantNames := []string{"tracked", "renamed", "renamed"}
+	if len(pending) != len(wantTypes) {
+		t.Fatalf("pending = %d events, want %d", len(pending), len(wantTypes))
+	}
+	for i, ev := range pending {
+		if ev.Type != wantTypes[i] {
+			t.Fatalf("event %d type = %s, want %s", i, ev.Type, wantTypes[i])
+		}
+		var payload struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+			t.Fatalf("event %d payload: %v", i, err)
+		}
+		if payload.ID != created.ID || payload.Name != wantNames[i] {
+			t.Fatalf("event %d payload = %+v, want id %s name %s", i, payload, created.ID, wantNames[i])
+		}
+	}
+
+	if limited, err := repo.FetchPending(ctx, 2); err != nil || len(limited) != 2 || limited[0].ID != pending[0].ID {
+		t.Fatalf("fetch limit 2: got %d events, err %v", len(limited), err)
+	}
+
+	if err := repo.MarkDelivered(ctx, []string{pending[0].ID, pending[1].ID}); err != nil {
+		t.Fatalf("mark delivered: %v", err)
+	}
+	rest, err := repo.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("fetch after mark: %v", err)
+	}
+	if len(rest) != 1 || rest[0].ID != pending[2].ID {
+		t.Fatalf("pending after mark = %d events, want only %s", len(rest), pending[2].ID)
+	}
+
+	// purge chỉ xoá event đã giao, event còn pending phải giữ nguyên
+	if err := repo.PurgeDelivered(ctx, time.Now().Add(time.Hour)); err != nil {
+		t.Fatalf("purge: %v", err)
+	}
+	if rest, err = repo.FetchPending(ctx, 10); err != nil || len(rest) != 1 {
+		t.Fatalf("pending after purge = %d events, err %v", len(rest), err)
+	}
+}
+
+func testOutboxClaim(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	seedItems(t, repo, 3)
+	pending, err := repo.FetchPending(ctx, 10)
+	if err != nil || len(pending) != 3 {
+		t.Fatalf("fetch pending: got %d events, err %v", len(pending), err)
+	}
+	ids := func(events []*domain.OutboxEvent) []string {
+		out := make([]string, len(events))
+		for i, ev := range events {
+			out[i] = ev.ID
+		}
+		return out
+	}
+	claim := func(owner string, limit int, lease time.Duration, want ...*domain.OutboxEvent) {
+		t.Helper()
+		got, err := repo.ClaimPending(ctx, owner, limit, lease)
+		if err != nil {
+			t.Fatalf("%s claim: %v", owner, err)
+		}
+		if fmt.Sprint(ids(got)) != fmt.Sprint(ids(want)) {
+			t.Fatalf("%s claimed %v, want %v", owner, ids(got), ids(want))
+		}
+	}
+
+	claim("a", 2, time.Hour, pending[0], pending[1])
+	claim("b", 10, 200*time.Millisecond, pending[2])
+	// owner nhận lại event mình đang giữ, không nhận event của owner khác
+	claim("a", 10, time.Hour, pending[0], pending[1])
+	time.Sleep(300 * time.Millisecond)
+	claim("a", 10, time.Hour, pending...)
+
+	// claim không làm event biến khỏi FetchPending, chỉ MarkDelivered mới làm
+	if err := repo.MarkDelivered(ctx, ids(pending)); err != nil {
+		t.Fatalf("mark delivered: %v", err)
+	}
+	claim("a", 10, time.Hour)
+
+	// nhiều owner claim đồng thời: mỗi event về đúng một owner
+	seedItems(t, repo, 20)
+	var (
+		mu      sync.Mutex
+		claimed = make(map[string]string)
+		wg      sync.WaitGroup
+	)
+	for i := 0; i < 4; i++ {
+		owner := fmt.Sprintf("relay-%d", i)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				events, err := repo.ClaimPending(ctx, owner, 3, time.Hour)
+				if err != nil {
+					t.Errorf("%s claim: %v", owner, err)
+					return
+				}
+				if len(events) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, ev := range events {
+					if prev, ok := claimed[ev.ID]; ok {
+						t.Errorf("event %s claimed by %s and %s", ev.ID, prev, owner)
+					}
+					claimed[ev.ID] = owner
+				}
+				mu.Unlock()
+				if err := repo.MarkDelivered(ctx, ids(events)); err != nil {
+					t.Errorf("%s mark delivered: %v", owner, err)
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	if len(claimed) != 20 {
+		t.Fatalf("claimed %d events, want 20", len(claimed))
+	}
+}
+
+// testOutboxFailedWrite: thao tác lỗi không được để lại event nào.
+func testOutboxFailedWrite(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	missing := "00000000-0000-0000-0000-000000000000"
+	name := "ghost"
+	if _, err := repo.Update(ctx, domain.Scope{}, missing, domain.ItemPatch{Name: &name}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("update missing: got %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, domain.Scope{}, missing); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("delete missing: got %v, want ErrNotFound", err)
+	}
+
+	pending, err := repo.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("fetch pending: %v", err)
+	}
+	if len(pending) != 0 {
+		t.Fatalf("pending = %d events, want 0", len(pending))
+	}
+}
+
+func testChangeFeed(t *testing.T, repo ItemRepository) {
+	feed, ok := repo.(ChangeFeed)
+	if !ok {
+		t.Skip("strategy has no change feed")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+	defer cancel()
+
+	events, err := feed.Watch(ctx)
+	if errors.Is(err, errors.ErrUnsupported) {
+		t.Skipf("change feed: %v", err)
+	}
+	if err != nil {
+		t.Fatalf("watch: %v", err)
+	}
+	// LISTEN/change stream được mở bất đồng bộ, chờ một chút trước khi ghi
+	time.Sleep(500 * time.Millisecond)
+
+	created, err := repo.Create(ctx, &domain.Item{Name: "watched"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	expectChange(t, events, domain.EventItemCreated, created.ID, "watched")
+
+	// tránh create và update cùng một mốc updated_at khi backend phải polling
+	time.Sleep(10 * time.Millisecond)
+	name := "rewatched"
+	if _, err := repo.Update(ctx, domain.Scope{}, created.ID, domain.ItemPatch{Name: &name}); err != nil {
+		t.Fatalf("update: %v", err)
+	}
+	expectChange(t, events, domain.EventItemUpdated, created.ID, name)
+
+	if err := repo.Delete(ctx, domain.Scope{}, created.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+	expectChange(t, events, domain.EventItemDeleted, created.ID, name)
+}
+
+func expectChange(t *testing.T, events <-chan domain.ChangeEvent, eventType, id, name string) {
+	t.Helper()
+	select {
+	case ev, ok := <-events:
+		if !ok {
+			t.Fatalf("change feed closed, want %s", eventType)
+		}
+		var payload struct {
+			ID   string `json:"id"`
+			Name string `json:"name"`
+		}
+		if err := json.Unmarshal(ev.Payload, &payload); err != nil {
+			t.Fatalf("%s payload: %v", ev.Type, err)
+		}
+		if ev.Type != eventType || payload.ID != id || payload.Name != name {
+			t.Fatalf("got %s %+v, want %s id %s name %s", ev.Type, payload, eventType, id, name)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatalf("no %s event from change feed", eventType)
+	}
+}
+
+func testScope(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	alice := domain.Scope{OwnerID: "alice"}
+	bob := domain.Scope{OwnerID: "bob"}
+	acme := domain.Scope{OwnerID: "carol", TenantID: "acme"}
+
+	create := func(name string, scope domain.Scope) *domain.Item {
+		t.Helper()
+		item, err := repo.Create(ctx, &domain.Item{Name: name, OwnerID: scope.OwnerID, TenantID: scope.TenantID})
+		if err != nil {
+			t.Fatalf("create %s: %v", name, err)
+		}
+		return item
+	}
+	aliceItem := create("alice-1", alice)
+	bobItem := create("bob-1", bob)
+	// item của dave trong tenant acme: carol cùng tenant nên thấy được
+	daveItem := create("dave-1", domain.Scope{OwnerID: "dave", TenantID: "acme"})
+
+	got, err := repo.GetByID(ctx, alice, aliceItem.ID)
+	if err != nil {
+		t.Fatalf("get own item: %v", err)
+	}
+	if got.OwnerID != "alice" || got.TenantID != "" {
+		t.Fatalf("owner/tenant not persisted: %+v", got)
+	}
+
+	list := func(scope domain.Scope) []*domain.Item {
+		t.Helper()
+		result, err := repo.List(ctx, scope, domain.ListParams{SortBy: "id", SortDir: "asc", PageSize: 100})
+		if err != nil {
+			t.Fatalf("list %+v: %v", scope, err)
+		}
+		if result.Total != int64(len(result.Items)) {
+			t.Fatalf("list %+v: total %d, items %d", scope, result.Total, len(result.Items))
+		}
+		return result.Items
+	}
+	assertSameIDs(t, "owner list", list(alice), sortedByID(aliceItem))
+	assertSameIDs(t, "tenant list", list(acme), sortedByID(daveItem))
+	assertSameIDs(t, "unscoped list", list(domain.Scope{}), sortedByID(aliceItem, bobItem, daveItem))
+
+	name := "hijacked"
+	if _, err := repo.GetByID(ctx, bob, aliceItem.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get other's item: got %v, want ErrNotFound", err)
+	}
+	if _, err := repo.Update(ctx, bob, aliceItem.ID, domain.ItemPatch{Name: &name}); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("update other's item: got %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, acme, bobItem.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("delete item outside tenant: got %v, want ErrNotFound", err)
+	}
+	// cùng tenant thì thấy item nhưng chỉ người tạo hoặc admin được sửa/xoá
+	if _, err := repo.GetByID(ctx, acme, daveItem.ID); err != nil {
+		t.Fatalf("get item in same tenant: %v", err)
+	}
+	if _, err := repo.Update(ctx, acme, daveItem.ID, domain.ItemPatch{Name: &name}); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("update other member's item: got %v, want ErrForbidden", err)
+	}
+	if err := repo.Delete(ctx, acme, daveItem.ID); !errors.Is(err, domain.ErrForbidden) {
+		t.Fatalf("delete other member's item: got %v, want ErrForbidden", err)
+	}
+	admin := acme
+	admin.Admin = true
+	if updated, err := repo.Update(ctx, admin, daveItem.ID, domain.ItemPatch{Name: &name}); err != nil || updated.OwnerID != "dave" {
+		t.Fatalf("update as tenant admin: got %+v, %v", updated, err)
+	}
+	if err := repo.Delete(ctx, alice, aliceItem.ID); err != nil {
+		t.Fatalf("delete own item: %v", err)
+	}
+
+	// event ITEM_DELETED phải mang owner để hub lọc được client
+	pending, err := repo.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("fetch pending: %v", err)
+	}
+	last := pending[len(pending)-1]
+	var payload map[string]string
+	if err := json.Unmarshal(last.Payload, &payload); err != nil {
+		t.Fatalf("payload: %v", err)
+	}
+	if last.Type != domain.EventItemDeleted || payload["owner_id"] != "alice" {
+		t.Fatalf("last event = %s %v, want ITEM_DELETED owned by alice", last.Type, payload)
+	}
+}
+
+// testTenantIsolation: cùng một owner ở hai tenant là hai người dùng khác nhau.
+func testTenantIsolation(t *testing.T, repo ItemRepository) {
+	ctx := context.Background()
+	acme := domain.Scope{OwnerID: "alice", TenantID: "acme"}
+	globex := domain.Scope{OwnerID: "alice", TenantID: "globex"}
+	personal := domain.Scope{OwnerID: "alice"}
+
+	acmeItem, err := repo.Create(ctx, &domain.Item{Name: "acme", OwnerID: "alice", TenantID: "acme"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	personalItem, err := repo.Create(ctx, &domain.Item{Name: "personal", OwnerID: "alice"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	for _, tc := range []struct {
+		label string
+		scope domain.Scope
+		want  []*domain.Item
+	}{
+		{"acme", acme, sortedByID(acmeItem)},
+		{"globex", globex, nil},
+		{"no tenant", personal, sortedByID(personalItem)},
+	} {
+		result, err := repo.List(ctx, tc.scope, domain.ListParams{SortBy: "id", SortDir: "asc", PageSize: 100})
+		if err != nil {
+			t.Fatalf("%s list: %v", tc.label, err)
+		}
+		assertSameIDs(t, tc.label+" list", result.Items, tc.want)
+	}
+
+	if _, err := repo.GetByID(ctx, globex, acmeItem.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get from other tenant: got %v, want ErrNotFound", err)
+	}
+	if _, err := repo.GetByID(ctx, personal, acmeItem.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get tenant item without tenant: got %v, want ErrNotFound", err)
+	}
+	if err := repo.Delete(ctx, acme, personalItem.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("delete personal item from tenant: got %v, want ErrNotFound", err)
+	}
+
+}
+
+func sortedByID(items ...*domain.Item) []*domain.Item {
+	sort.Slice(items, func(i, j int) bool { return items[i].ID < items[j].ID })
+	return items
+}
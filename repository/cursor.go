@@ -0,0 +1,144 @@
+package repository
+
+import (
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// resolveCursor decode cursor trong params (nếu có) và ép sort_by/sort_dir
+// theo cursor, để trang tiếp theo luôn cùng thứ tự với trang trước.
+func resolveCursor(params *domain.ListParams) (*domain.Cursor, error) {
+	normalizeSort(params)
+	if params.Cursor == "" {
+		return nil, nil
+	}
+
+	cursor, err := domain.DecodeCursor(params.Cursor)
+	if err != nil {
+		return nil, err
+	}
+	params.SortBy, params.SortDir = cursor.SortBy, cursor.SortDir
+	normalizeSort(params)
+	cursor.SortBy, cursor.SortDir = params.SortBy, params.SortDir
+
+	return cursor, nil
+}
+
+// queryDir là chiều sort thực tế gửi xuống DB: lật ngược khi đi lùi.
+func queryDir(params domain.ListParams, cursor *domain.Cursor) string {
+	if cursor == nil || !cursor.Backward {
+		return params.SortDir
+	}
+	if params.SortDir == "asc" {
+		return "desc"
+	}
+	return "asc"
+}
+
+func isTimeSortField(field string) bool {
+	return field == "created_at" || field == "updated_at"
+}
+
+func cursorArg(cursor *domain.Cursor) (interface{}, error) {
+	if !isTimeSortField(cursor.SortBy) {
+		return cursor.Value, nil
+	}
+	t, err := time.Parse(time.RFC3339Nano, cursor.Value)
+	if err != nil {
+		return nil, fmt.Errorf("%w: invalid cursor", domain.ErrValidation)
+	}
+	return t, nil
+}
+
+func cursorValue(item *domain.Item, field string) string {
+	switch field {
+	case "created_at":
+		return item.CreatedAt.UTC().Format(time.RFC3339Nano)
+	case "updated_at":
+		return item.UpdatedAt.UTC().Format(time.RFC3339Nano)
+	case "name":
+		return item.Name
+	default:
+		return item.ID
+	}
+}
+
+// buildKeysetCondition trả về điều kiện WHERE dạng
+// (field < v OR (field = v AND id < i)), viết bằng OR thay vì row-value
+// để chạy được trên mọi dialect.
+func buildKeysetCondition(params domain.ListParams, cursor *domain.Cursor) (string, []interface{}, error) {
+	value, err := cursorArg(cursor)
+	if err != nil {
+		return "", nil, err
+	}
+
+	op := "<"
+	if queryDir(params, cursor) == "asc" {
+		op = ">"
+	}
+
+	if params.SortBy == "id" {
+		return fmt.Sprintf("id %s ?", op), []interface{}{cursor.ID}, nil
+	}
+	cond := fmt.Sprintf("(%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?))", params.SortBy, op)
+	return cond, []interface{}{value, value, cursor.ID}, nil
+}
+
+// paginate nhận tối đa PageSize+1 item theo thứ tự query và dựng ListResult
+// kèm next/prev cursor. Item thừa chỉ dùng để biết còn trang kế tiếp hay không.
+func paginate(items []*domain.Item, total int64, params domain.ListParams, cursor *domain.Cursor) *domain.ListResult {
+	hasMore := len(items) > params.PageSize
+	if hasMore {
+		items = items[:params.PageSize]
+	}
+
+	backward := cursor != nil && cursor.Backward
+	if backward {
+		for i, j := 0, len(items)-1; i < j; i, j = i+1, j-1 {
+			items[i], items[j] = items[j], items[i]
+		}
+	}
+
+	result := &domain.ListResult{
+		Items:      items,
+		Total:      total,
+		Page:       params.Page,
+		PageSize:   params.PageSize,
+		TotalPages: int(math.Ceil(float64(total) / float64(params.PageSize))),
+	}
+	if cursor != nil {
+		result.Page = 0
+	}
+	if len(items) == 0 {
+		return result
+	}
+
+	hasNext, hasPrev := hasMore, params.Page > 1
+	switch {
+	case backward:
+		hasNext, hasPrev = true, hasMore
+	case cursor != nil:
+		hasPrev = true
+	}
+
+	if hasNext {
+		result.NextCursor = newCursor(items[len(items)-1], params, false)
+	}
+	if hasPrev {
+		result.PrevCursor = newCursor(items[0], params, true)
+	}
+	return result
+}
+
+func newCursor(item *domain.Item, params domain.ListParams, backward bool) string {
+	return domain.Cursor{
+		SortBy:   params.SortBy,
+		SortDir:  params.SortDir,
+		Value:    cursorValue(item, params.SortBy),
+		ID:       item.ID,
+		Backward: backward,
+	}.Encode()
+}
@@ -1,45 +1,160 @@
 package repository
 
 import (
+	"context"
 	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/mongo/options"
+
+	"github.com/JIeeiroSst/hub/domain"
 )
 
 type DBConfig struct {
 	Type          DBType
-	DSN           string // dùng cho Postgres, MySQL
+	DSN           string // dùng cho Postgres, MySQL, SQLite
 	MongoURI      string // dùng cho MongoDB
 	MongoDBName   string
 	MongoCollName string
+
+	// Migrate quyết định xử lý schema lúc khởi động, mặc định MigrateCheck.
+	Migrate MigrateMode
+
+	// TenantDSNs cho tenant dùng database riêng: giá trị thay cho DSN (SQL,
+	// schema riêng thì thêm search_path vào DSN Postgres) hoặc MongoDBName.
+	// Tenant không có trong map dùng database chung.
+	TenantDSNs map[string]string
+
+	// Pool áp dụng cho từng kết nối (database chung và mỗi database tenant).
+	Pool PoolConfig
 }
 
+// PoolConfig chỉnh connection pool; giá trị 0 giữ mặc định của driver.
+// SQLite luôn dùng một connection (xem sqliteDialect) nên bỏ qua Pool.
+type PoolConfig struct {
+	MaxOpenConns    int
+	MaxIdleConns    int
+	ConnMaxLifetime time.Duration
+	ConnMaxIdleTime time.Duration
+}
+
+// mongoOptions: MongoDB không có idle pool riêng nên MaxIdleConns và
+// ConnMaxLifetime không dùng tới.
+func (p PoolConfig) mongoOptions() *options.ClientOptions {
+	opts := options.Client()
+	if p.MaxOpenConns > 0 {
+		opts.SetMaxPoolSize(uint64(p.MaxOpenConns))
+	}
+	if p.ConnMaxIdleTime > 0 {
+		opts.SetMaxConnIdleTime(p.ConnMaxIdleTime)
+	}
+	return opts
+}
 
 func NewRepository(cfg DBConfig) (ItemRepository, error) {
+	repo, err := openRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	if m, ok := repo.(migratable); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+		defer cancel()
+		if err := ensureSchema(ctx, m.Migrator(), cfg.Migrate); err != nil {
+			return nil, fmt.Errorf("%s schema error: %w", cfg.Type, err)
+		}
+	}
+	return repo, nil
+}
+
+func openRepository(cfg DBConfig) (ItemRepository, error) {
+	if len(cfg.TenantDSNs) == 0 {
+		return openStrategy(cfg)
+	}
+
+	shared, err := openStrategy(cfg)
+	if err != nil {
+		return nil, err
+	}
+	tenants := make(map[string]ItemRepository, len(cfg.TenantDSNs))
+	for tenantID, dsn := range cfg.TenantDSNs {
+		if !domain.ValidTenantID(tenantID) {
+			return nil, fmt.Errorf("invalid tenant id %q", tenantID)
+		}
+		tcfg := cfg
+		if cfg.Type == DBTypeMongoDB {
+			tcfg.MongoDBName = dsn
+		} else {
+			tcfg.DSN = dsn
+		}
+		repo, err := openStrategy(tcfg)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", tenantID, err)
+		}
+		tenants[tenantID] = repo
+	}
+	return NewTenantRouter(shared, tenants), nil
+}
+
+func openStrategy(cfg DBConfig) (ItemRepository, error) {
 	switch cfg.Type {
 	case DBTypePostgres:
-		return NewPostgresStrategy(cfg.DSN)
+		r, err := NewPostgresStrategy(cfg.DSN)
+		return withPool(r, err, cfg.Pool)
 
 	case DBTypeMySQL:
-		return NewMySQLStrategy(cfg.DSN)
+		r, err := NewMySQLStrategy(cfg.DSN)
+		return withPool(r, err, cfg.Pool)
+
+	case DBTypeSQLite:
+		return NewSQLiteStrategy(cfg.DSN)
 
 	case DBTypeMongoDB:
-		return NewMongoDBStrategy(cfg.MongoURI, cfg.MongoDBName, cfg.MongoCollName)
+		return NewMongoDBStrategy(cfg.MongoURI, cfg.MongoDBName, cfg.MongoCollName, cfg.Pool.mongoOptions())
+
+	case DBTypeMemory:
+		return NewMemoryStrategy(), nil
 
 	default:
 		return nil, fmt.Errorf("unsupported db type: %s", cfg.Type)
 	}
 }
 
-func buildOrderClause(sortBy, sortDir string) string {
-	allowedFields := map[string]string{
-		"created_at": "created_at",
-		"updated_at": "updated_at",
-		"name":       "name",
-		"id":         "id",
+// withPool áp dụng pool cho strategy vừa mở, đóng lại nếu không chỉnh được.
+func withPool(r *GormStrategy, err error, p PoolConfig) (ItemRepository, error) {
+	if err != nil {
+		return nil, err
+	}
+	if err := r.setPool(p); err != nil {
+		r.Close(context.Background())
+		return nil, err
+	}
+	return r, nil
+}
+
+var allowedSortFields = map[string]string{
+	"created_at": "created_at",
+	"updated_at": "updated_at",
+	"name":       "name",
+	"id":         "id",
+}
+
+func normalizeSort(params *domain.ListParams) {
+	field, ok := allowedSortFields[params.SortBy]
+	if !ok {
+		field = "created_at"
 	}
+	params.SortBy = field
 
-	field, ok := allowedFields[sortBy]
+	if params.SortDir != "asc" {
+		params.SortDir = "desc"
+	}
+}
+
+func buildOrderClause(sortBy, sortDir string) string {
+	field, ok := allowedSortFields[sortBy]
 	if !ok {
-		field = "created_at" 
+		field = "created_at"
 	}
 
 	dir := "DESC"
@@ -47,5 +162,19 @@ func buildOrderClause(sortBy, sortDir string) string {
 		dir = "ASC"
 	}
 
-	return fmt.Sprintf("%s %s", field, dir)
+	if field == "id" {
+		return fmt.Sprintf("id %s", dir)
+	}
+	return fmt.Sprintf("%s %s, id %s", field, dir, dir)
+}
+
+func buildUpdateColumns(patch domain.ItemPatch) map[string]interface{} {
+	cols := make(map[string]interface{}, 2)
+	if patch.Name != nil {
+		cols["name"] = *patch.Name
+	}
+	if patch.Content != nil {
+		cols["content"] = *patch.Content
+	}
+	return cols
 }
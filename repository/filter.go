@@ -0,0 +1,95 @@
+package repository
+
+import (
+	"fmt"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"gorm.io/gorm"
+)
+
+// applyGormFilters áp q + các filter thời gian lên query.
+func applyGormFilters(db *gorm.DB, params domain.ListParams, search func(db *gorm.DB, q string) *gorm.DB) *gorm.DB {
+	if params.Q != "" {
+		db = search(db, params.Q)
+	}
+	if !params.CreatedAfter.IsZero() {
+		db = db.Where("created_at > ?", params.CreatedAfter.UTC())
+	}
+	if !params.CreatedBefore.IsZero() {
+		db = db.Where("created_at < ?", params.CreatedBefore.UTC())
+	}
+	if !params.UpdatedAfter.IsZero() {
+		db = db.Where("updated_at > ?", params.UpdatedAfter.UTC())
+	}
+	return db
+}
+
+// applyGormScope giới hạn query theo scope, cùng quy tắc với domain.Scope.Allows.
+func applyGormScope(db *gorm.DB, scope domain.Scope) *gorm.DB {
+	if scope.IsZero() {
+		return db
+	}
+	db = db.Where("tenant_id = ?", scope.TenantID)
+	if scope.TenantID == "" {
+		db = db.Where("owner_id = ?", scope.OwnerID)
+	}
+	return db
+}
+
+// checkModify chạy sau khi item đã được tìm thấy trong scope: item thấy được
+// nhưng thuộc người khác trả về ErrForbidden thay vì ErrNotFound.
+func checkModify(scope domain.Scope, item *domain.Item) error {
+	if !scope.CanModify(item.OwnerID, item.TenantID) {
+		return fmt.Errorf("%w: item %s belongs to another user", domain.ErrForbidden, item.ID)
+	}
+	return nil
+}
+
+// checkUpsert kiểm tra row đã có trước khi Upsert ghi đè: item ngoài scope
+// thì id đã bị dùng (ErrConflict), item của người khác thì ErrForbidden.
+func checkUpsert(scope domain.Scope, existing *domain.Item) error {
+	if !scope.Allows(existing.OwnerID, existing.TenantID) {
+		return fmt.Errorf("%w: id %s is already in use", domain.ErrConflict, existing.ID)
+	}
+	return checkModify(scope, existing)
+}
+
+func buildMongoScope(scope domain.Scope) bson.D {
+	if scope.IsZero() {
+		return bson.D{}
+	}
+	// document tạo trước khi có tenant không có field tenant_id
+	tenant := interface{}(scope.TenantID)
+	if scope.TenantID == "" {
+		tenant = bson.M{"$in": bson.A{"", nil}}
+	}
+	filter := bson.D{{Key: "tenant_id", Value: tenant}}
+	if scope.TenantID == "" {
+		filter = append(filter, bson.E{Key: "owner_id", Value: scope.OwnerID})
+	}
+	return filter
+}
+
+func buildMongoFilter(scope domain.Scope, params domain.ListParams) bson.D {
+	filter := buildMongoScope(scope)
+	if params.Q != "" {
+		filter = append(filter, bson.E{Key: "$text", Value: bson.M{"$search": params.Q}})
+	}
+
+	created := bson.M{}
+	if !params.CreatedAfter.IsZero() {
+		created["$gt"] = params.CreatedAfter
+	}
+	if !params.CreatedBefore.IsZero() {
+		created["$lt"] = params.CreatedBefore
+	}
+	if len(created) > 0 {
+		filter = append(filter, bson.E{Key: "created_at", Value: created})
+	}
+
+	if !params.UpdatedAfter.IsZero() {
+		filter = append(filter, bson.E{Key: "updated_at", Value: bson.M{"$gt": params.UpdatedAfter}})
+	}
+	return filter
+}
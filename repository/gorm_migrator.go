@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const createSchemaMigrationsSQL = `CREATE TABLE IF NOT EXISTS schema_migrations (
+    version    BIGINT       PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    applied_at TIMESTAMP    NOT NULL
+)`
+
+type schemaMigration struct {
+	Version   int `gorm:"primaryKey;autoIncrement:false"`
+	Name      string
+	AppliedAt time.Time
+}
+
+func (schemaMigration) TableName() string { return "schema_migrations" }
+
+type gormMigrator struct {
+	db      *gorm.DB
+	dialect string
+}
+
+func (r *GormStrategy) Migrator() Migrator {
+	return &gormMigrator{db: r.db, dialect: r.dialect.Name}
+}
+
+func (m *gormMigrator) Close() error {
+	sqlDB, err := m.db.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("%s close error: %w", m.dialect, err)
+	}
+	return nil
+}
+
+func (m *gormMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := loadSQLMigrations(m.dialect)
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(migrations))
+	for i, mig := range migrations {
+		status[i] = MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			status[i].Applied = true
+			status[i].AppliedAt = row.AppliedAt
+		}
+	}
+	return status, nil
+}
+
+func (m *gormMigrator) Up(ctx context.Context) (int, error) {
+	migrations, err := loadSQLMigrations(m.dialect)
+	if err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mig := range migrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		// MySQL commit ngầm sau mỗi DDL, transaction ở đây chỉ thực sự
+		// nguyên tử trên Postgres/SQLite.
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := execStatements(tx, mig.Up); err != nil {
+				return err
+			}
+			return tx.Create(&schemaMigration{
+				Version:   mig.Version,
+				Name:      mig.Name,
+				AppliedAt: time.Now().UTC(),
+			}).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("%s migrate up %04d_%s error: %w", m.dialect, mig.Version, mig.Name, translateGormError(err))
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (m *gormMigrator) Down(ctx context.Context, steps int) (int, error) {
+	migrations, err := loadSQLMigrations(m.dialect)
+	if err != nil {
+		return 0, err
+	}
+	applied, err := m.applied(ctx, true)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations) - 1; i >= 0 && count < steps; i-- {
+		mig := migrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		err := m.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+			if err := execStatements(tx, mig.Down); err != nil {
+				return err
+			}
+			return tx.Delete(&schemaMigration{}, "version = ?", mig.Version).Error
+		})
+		if err != nil {
+			return count, fmt.Errorf("%s migrate down %04d_%s error: %w", m.dialect, mig.Version, mig.Name, translateGormError(err))
+		}
+		count++
+	}
+	return count, nil
+}
+
+// applied trả về các version đã chạy. create=false dùng cho Status để
+// chế độ check không tạo bảng gì trên DB production.
+func (m *gormMigrator) applied(ctx context.Context, create bool) (map[int]schemaMigration, error) {
+	db := m.db.WithContext(ctx)
+	if create {
+		if err := db.Exec(createSchemaMigrationsSQL).Error; err != nil {
+			return nil, fmt.Errorf("%s schema_migrations error: %w", m.dialect, translateGormError(err))
+		}
+	} else if !db.Migrator().HasTable(&schemaMigration{}) {
+		return map[int]schemaMigration{}, nil
+	}
+
+	var rows []schemaMigration
+	if err := db.Find(&rows).Error; err != nil {
+		return nil, fmt.Errorf("%s schema_migrations error: %w", m.dialect, translateGormError(err))
+	}
+
+	applied := make(map[int]schemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
+
+func execStatements(tx *gorm.DB, sql string) error {
+	for _, stmt := range splitStatements(sql) {
+		if err := tx.Exec(stmt).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"gorm.io/gorm"
+)
+
+type outboxRow struct {
+	ID          int64 `gorm:"primaryKey"`
+	EventType   string
+	Payload     string
+	CreatedAt   time.Time
+	DeliveredAt *time.Time
+	// relay đang giữ event và hạn lease, xem ClaimPending
+	ClaimedBy    *string
+	ClaimedUntil *time.Time
+}
+
+func (outboxRow) TableName() string { return "outbox" }
+
+func insertOutbox(tx *gorm.DB, eventType string, payload interface{}) error {
+	data, err := marshalOutboxPayload(payload)
+	if err != nil {
+		return err
+	}
+	return tx.Create(&outboxRow{EventType: eventType, Payload: data}).Error
+}
+
+func (r *GormStrategy) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	var rows []outboxRow
+	err := r.db.WithContext(ctx).
+		Where("delivered_at IS NULL").
+		Order("id").
+		Limit(limit).
+		Find(&rows).Error
+	if err != nil {
+		return nil, r.wrap("outbox fetch", err)
+	}
+	return outboxEvents(rows), nil
+}
+
+func outboxEvents(rows []outboxRow) []*domain.OutboxEvent {
+	events := make([]*domain.OutboxEvent, len(rows))
+	for i, row := range rows {
+		events[i] = &domain.OutboxEvent{
+			ID:        strconv.FormatInt(row.ID, 10),
+			Type:      row.EventType,
+			Payload:   []byte(row.Payload),
+			CreatedAt: row.CreatedAt,
+		}
+	}
+	return events
+}
+
+// claimableSQL: event chưa giao, chưa ai giữ, lease đã hết hạn hoặc chính
+// owner đang giữ (relay thử lại event broadcast lỗi ở lần trước).
+const claimableSQL = "delivered_at IS NULL AND (claimed_until IS NULL OR claimed_until < ? OR claimed_by = ?)"
+
+func (r *GormStrategy) ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*domain.OutboxEvent, error) {
+	now := r.db.NowFunc()
+	var ids []int64
+	err := r.db.WithContext(ctx).
+		Model(&outboxRow{}).
+		Where(claimableSQL, now, owner).
+		Order("id").
+		Limit(limit).
+		Pluck("id", &ids).Error
+	if err != nil {
+		return nil, r.wrap("outbox claim", err)
+	}
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	// UPDATE kiểm tra lại điều kiện trên từng dòng (postgres/mysql khoá dòng,
+	// sqlite chỉ có một writer) nên hai relay chọn trùng event thì chỉ một
+	// bên claim được, bên kia đọc lại thấy dòng đó đã thuộc owner khác.
+	err = r.db.WithContext(ctx).
+		Model(&outboxRow{}).
+		Where("id IN ?", ids).
+		Where(claimableSQL, now, owner).
+		Updates(map[string]interface{}{"claimed_by": owner, "claimed_until": now.Add(lease)}).Error
+	if err != nil {
+		return nil, r.wrap("outbox claim", err)
+	}
+
+	var rows []outboxRow
+	err = r.db.WithContext(ctx).
+		Where("id IN ? AND claimed_by = ? AND delivered_at IS NULL", ids, owner).
+		Order("id").
+		Find(&rows).Error
+	if err != nil {
+		return nil, r.wrap("outbox claim", err)
+	}
+	return outboxEvents(rows), nil
+}
+
+func (r *GormStrategy) MarkDelivered(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]int64, 0, len(ids))
+	for _, id := range ids {
+		key, err := strconv.ParseInt(id, 10, 64)
+		if err != nil {
+			return r.wrap("outbox mark", err)
+		}
+		keys = append(keys, key)
+	}
+
+	now := r.db.NowFunc()
+	err := r.db.WithContext(ctx).
+		Model(&outboxRow{}).
+		Where("id IN ?", keys).
+		Update("delivered_at", now).Error
+	if err != nil {
+		return r.wrap("outbox mark", err)
+	}
+	return nil
+}
+
+func (r *GormStrategy) PurgeDelivered(ctx context.Context, before time.Time) error {
+	err := r.db.WithContext(ctx).
+		Where("delivered_at IS NOT NULL AND delivered_at < ?", before.UTC()).
+		Delete(&outboxRow{}).Error
+	if err != nil {
+		return r.wrap("outbox purge", err)
+	}
+	return nil
+}
@@ -0,0 +1,283 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// GormDialect gom những phần khác nhau giữa các SQL backend; mọi logic còn
+// lại nằm chung trong GormStrategy.
+type GormDialect struct {
+	// Name dùng làm prefix cho error message và là thư mục migrations/<Name>.
+	Name string
+
+	// Search thêm điều kiện full-text cho q vào query.
+	Search func(db *gorm.DB, q string) *gorm.DB
+
+	// LockRows bật SELECT ... FOR UPDATE khi đọc row trước khi update.
+	LockRows bool
+
+	// Upsert trả về mệnh đề cho INSERT của Upsert: trùng id thì chỉ cập nhật
+	// các cột trong update.
+	Upsert func(update []string) clause.Expression
+
+	// NowFunc ghi đè gorm.Config.NowFunc nếu khác nil.
+	NowFunc func() time.Time
+
+	// Setup chạy ngay sau khi mở kết nối, ví dụ để chỉnh connection pool.
+	Setup func(db *gorm.DB) error
+
+	// Watch đọc thay đổi trên items và gửi vào out tới khi ctx bị huỷ.
+	// nil nghĩa là dialect không có change feed.
+	Watch func(ctx context.Context, r *GormStrategy, out chan<- domain.ChangeEvent)
+}
+
+type GormStrategy struct {
+	db      *gorm.DB
+	dialect GormDialect
+}
+
+func NewGormStrategy(dialector gorm.Dialector, dialect GormDialect) (*GormStrategy, error) {
+	db, err := gorm.Open(dialector, &gorm.Config{
+		TranslateError: true,
+		NowFunc:        dialect.NowFunc,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%s connect error: %w", dialect.Name, translateGormError(err))
+	}
+
+	if dialect.Setup != nil {
+		if err := dialect.Setup(db); err != nil {
+			return nil, fmt.Errorf("%s connect error: %w", dialect.Name, translateGormError(err))
+		}
+	}
+
+	return &GormStrategy{db: db, dialect: dialect}, nil
+}
+
+func (r *GormStrategy) Create(ctx context.Context, item *domain.Item) (*domain.Item, error) {
+	row := &sqlItem{
+		ID:       uuid.NewString(),
+		Name:     item.Name,
+		Content:  item.Content,
+		OwnerID:  item.OwnerID,
+		TenantID: item.TenantID,
+	}
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(row).Error; err != nil {
+			return err
+		}
+		return insertOutbox(tx, domain.EventItemCreated, toDomainItem(row))
+	})
+	if err != nil {
+		return nil, r.wrap("create", err)
+	}
+	return toDomainItem(row), nil
+}
+
+func (r *GormStrategy) List(ctx context.Context, scope domain.Scope, params domain.ListParams) (*domain.ListResult, error) {
+	params.SetDefaults()
+
+	cursor, err := resolveCursor(&params)
+	if err != nil {
+		return nil, err
+	}
+
+	var rows []sqlItem
+	var total int64
+
+	if err := r.filtered(ctx, scope, params).Model(&sqlItem{}).Count(&total).Error; err != nil {
+		return nil, r.wrap("count", err)
+	}
+
+	query := r.filtered(ctx, scope, params).
+		Order(buildOrderClause(params.SortBy, queryDir(params, cursor))).
+		Limit(params.PageSize + 1)
+
+	if cursor != nil {
+		cond, args, err := buildKeysetCondition(params, cursor)
+		if err != nil {
+			return nil, err
+		}
+		query = query.Where(cond, args...)
+	} else {
+		query = query.Offset((params.Page - 1) * params.PageSize)
+	}
+
+	if err := query.Find(&rows).Error; err != nil {
+		return nil, r.wrap("list", err)
+	}
+
+	items := make([]*domain.Item, len(rows))
+	for i := range rows {
+		items[i] = toDomainItem(&rows[i])
+	}
+
+	return paginate(items, total, params, cursor), nil
+}
+
+func (r *GormStrategy) GetByID(ctx context.Context, scope domain.Scope, id string) (*domain.Item, error) {
+	var row sqlItem
+	if err := applyGormScope(r.db.WithContext(ctx), scope).First(&row, "id = ?", id).Error; err != nil {
+		return nil, r.wrap("get by id", err)
+	}
+	return toDomainItem(&row), nil
+}
+
+func (r *GormStrategy) Update(ctx context.Context, scope domain.Scope, id string, patch domain.ItemPatch) (*domain.Item, error) {
+	var row sqlItem
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		if err := applyGormScope(r.locked(tx), scope).First(&row, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := checkModify(scope, toDomainItem(&row)); err != nil {
+			return err
+		}
+		if err := tx.Model(&row).Updates(buildUpdateColumns(patch)).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&row, "id = ?", id).Error; err != nil {
+			return err
+		}
+		return insertOutbox(tx, domain.EventItemUpdated, toDomainItem(&row))
+	})
+	if err != nil {
+		return nil, r.wrap("update", err)
+	}
+	return toDomainItem(&row), nil
+}
+
+// Upsert đọc (và khoá) row trước để kiểm tra quyền và biết event là
+// ITEM_CREATED hay ITEM_UPDATED; INSERT dùng mệnh đề Upsert của dialect nên
+// hai request cùng tạo một id không làm request sau lỗi duplicate key.
+func (r *GormStrategy) Upsert(ctx context.Context, scope domain.Scope, item *domain.Item) (*domain.Item, bool, error) {
+	row := sqlItem{
+		ID:       item.ID,
+		Name:     item.Name,
+		Content:  item.Content,
+		OwnerID:  item.OwnerID,
+		TenantID: item.TenantID,
+	}
+	var created bool
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var existing []sqlItem
+		if err := r.locked(tx).Limit(1).Find(&existing, "id = ?", item.ID).Error; err != nil {
+			return err
+		}
+		created = len(existing) == 0
+		if !created {
+			if err := checkUpsert(scope, toDomainItem(&existing[0])); err != nil {
+				return err
+			}
+			row.OwnerID, row.TenantID = existing[0].OwnerID, existing[0].TenantID
+		}
+
+		if err := tx.Clauses(r.dialect.Upsert([]string{"name", "content", "updated_at"})).Create(&row).Error; err != nil {
+			return err
+		}
+		if err := tx.First(&row, "id = ?", item.ID).Error; err != nil {
+			return err
+		}
+		eventType := domain.EventItemUpdated
+		if created {
+			eventType = domain.EventItemCreated
+		}
+		return insertOutbox(tx, eventType, toDomainItem(&row))
+	})
+	if err != nil {
+		return nil, false, r.wrap("upsert", err)
+	}
+	return toDomainItem(&row), created, nil
+}
+
+func (r *GormStrategy) Delete(ctx context.Context, scope domain.Scope, id string) error {
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		var row sqlItem
+		if err := applyGormScope(r.locked(tx), scope).First(&row, "id = ?", id).Error; err != nil {
+			return err
+		}
+		if err := checkModify(scope, toDomainItem(&row)); err != nil {
+			return err
+		}
+		res := tx.Delete(&sqlItem{}, "id = ?", id)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		return insertOutbox(tx, domain.EventItemDeleted, deletedPayload(toDomainItem(&row)))
+	})
+	if err != nil {
+		return r.wrap("delete", err)
+	}
+	return nil
+}
+
+func (r *GormStrategy) Ping(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
+
+func (r *GormStrategy) setPool(p PoolConfig) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return fmt.Errorf("%s pool error: %w", r.dialect.Name, err)
+	}
+	if p.MaxOpenConns > 0 {
+		sqlDB.SetMaxOpenConns(p.MaxOpenConns)
+	}
+	if p.MaxIdleConns > 0 {
+		sqlDB.SetMaxIdleConns(p.MaxIdleConns)
+	}
+	if p.ConnMaxLifetime > 0 {
+		sqlDB.SetConnMaxLifetime(p.ConnMaxLifetime)
+	}
+	if p.ConnMaxIdleTime > 0 {
+		sqlDB.SetConnMaxIdleTime(p.ConnMaxIdleTime)
+	}
+	return nil
+}
+
+func (r *GormStrategy) Close(ctx context.Context) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	if err := sqlDB.Close(); err != nil {
+		return fmt.Errorf("%s close error: %w", r.dialect.Name, err)
+	}
+	return nil
+}
+
+func (r *GormStrategy) filtered(ctx context.Context, scope domain.Scope, params domain.ListParams) *gorm.DB {
+	return applyGormFilters(applyGormScope(r.db.WithContext(ctx), scope), params, r.dialect.Search)
+}
+
+func (r *GormStrategy) locked(tx *gorm.DB) *gorm.DB {
+	if !r.dialect.LockRows {
+		return tx
+	}
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"})
+}
+
+// onConflictUpsert: INSERT ... ON CONFLICT (id) DO UPDATE, dùng cho postgres và sqlite.
+func onConflictUpsert(update []string) clause.Expression {
+	return clause.OnConflict{
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns(update),
+	}
+}
+
+func (r *GormStrategy) wrap(op string, err error) error {
+	return fmt.Errorf("%s %s error: %w", r.dialect.Name, op, translateGormError(err))
+}
@@ -2,16 +2,51 @@ package repository
 
 import (
 	"context"
+	"time"
 
 	"github.com/JIeeiroSst/hub/domain"
 )
 
-
+// ItemRepository ghi một OutboxEvent trong cùng transaction với mỗi thay đổi
+// của item (Create/Update/Delete); OutboxStore cho relay đọc lại các event đó.
 type ItemRepository interface {
+	OutboxStore
+
+	// Create lưu cả OwnerID/TenantID của item. Các method còn lại chỉ thấy
+	// item nằm trong scope; item ngoài scope được coi như không tồn tại
+	// (ErrNotFound) để không lộ sự tồn tại của nó.
 	Create(ctx context.Context, item *domain.Item) (*domain.Item, error)
-	List(ctx context.Context, params domain.ListParams) (*domain.ListResult, error)
-	GetByID(ctx context.Context, id string) (*domain.Item, error)
+	List(ctx context.Context, scope domain.Scope, params domain.ListParams) (*domain.ListResult, error)
+	GetByID(ctx context.Context, scope domain.Scope, id string) (*domain.Item, error)
+	Update(ctx context.Context, scope domain.Scope, id string, patch domain.ItemPatch) (*domain.Item, error)
+	Delete(ctx context.Context, scope domain.Scope, id string) error
+	// Upsert ghi đè name/content của item có item.ID, chưa có thì tạo mới với
+	// OwnerID/TenantID của item; created báo item vừa được tạo. Item đã có
+	// nhưng ngoài scope trả về ErrConflict, của người khác thì ErrForbidden.
+	Upsert(ctx context.Context, scope domain.Scope, item *domain.Item) (result *domain.Item, created bool, err error)
 	Ping(ctx context.Context) error
+	// Close đóng connection pool; gọi sau khi mọi request và relay đã dừng.
+	Close(ctx context.Context) error
+}
+
+type OutboxStore interface {
+	// FetchPending trả về tối đa limit event chưa giao, theo thứ tự ghi.
+	FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error)
+	// ClaimPending giống FetchPending nhưng giữ event cho owner trong lease:
+	// relay khác gọi cùng lúc không nhận lại các event này cho tới khi lease
+	// hết hạn, còn chính owner thì nhận lại được. Nhiều replica chạy relay
+	// trên cùng outbox nhờ đó không broadcast trùng event.
+	ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*domain.OutboxEvent, error)
+	MarkDelivered(ctx context.Context, ids []string) error
+	// PurgeDelivered xoá các event đã giao trước thời điểm before.
+	PurgeDelivered(ctx context.Context, before time.Time) error
+}
+
+// ChangeFeed phát event cho mọi thay đổi trên items, kể cả khi dữ liệu được
+// ghi bởi service khác hay psql. Channel đóng khi ctx bị huỷ. Backend không hỗ
+// trợ trả về lỗi bọc errors.ErrUnsupported.
+type ChangeFeed interface {
+	Watch(ctx context.Context) (<-chan domain.ChangeEvent, error)
 }
 
 type DBType string
@@ -20,4 +55,6 @@ const (
 	DBTypePostgres DBType = "postgres"
 	DBTypeMySQL    DBType = "mysql"
 	DBTypeMongoDB  DBType = "mongodb"
+	DBTypeSQLite   DBType = "sqlite"
+	DBTypeMemory   DBType = "memory"
 )
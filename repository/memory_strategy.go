@@ -0,0 +1,373 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"github.com/google/uuid"
+)
+
+// MemoryStrategy lưu item trong RAM, dùng cho test và chạy local không cần DB.
+// An toàn khi gọi đồng thời từ nhiều goroutine.
+type MemoryStrategy struct {
+	mu     sync.RWMutex
+	items  map[string]*domain.Item
+	outbox []memoryOutboxEvent
+	nextID int64
+}
+
+type memoryOutboxEvent struct {
+	event        domain.OutboxEvent
+	deliveredAt  time.Time
+	claimedBy    string
+	claimedUntil time.Time
+}
+
+func NewMemoryStrategy() *MemoryStrategy {
+	return &MemoryStrategy{items: make(map[string]*domain.Item)}
+}
+
+func (r *MemoryStrategy) Create(ctx context.Context, item *domain.Item) (*domain.Item, error) {
+	now := time.Now().UTC()
+	row := &domain.Item{
+		ID:        uuid.NewString(),
+		Name:      item.Name,
+		Content:   item.Content,
+		OwnerID:   item.OwnerID,
+		TenantID:  item.TenantID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if err := r.appendOutbox(domain.EventItemCreated, row); err != nil {
+		return nil, err
+	}
+	r.items[row.ID] = row
+
+	return copyItem(row), nil
+}
+
+func (r *MemoryStrategy) List(ctx context.Context, scope domain.Scope, params domain.ListParams) (*domain.ListResult, error) {
+	params.SetDefaults()
+
+	cursor, err := resolveCursor(&params)
+	if err != nil {
+		return nil, err
+	}
+
+	var terms []string
+	if params.Q != "" {
+		terms = strings.Fields(strings.ToLower(params.Q))
+	}
+
+	r.mu.RLock()
+	matched := make([]*domain.Item, 0, len(r.items))
+	for _, item := range r.items {
+		if scope.Allows(item.OwnerID, item.TenantID) && matchesFilters(item, params, terms) {
+			matched = append(matched, copyItem(item))
+		}
+	}
+	r.mu.RUnlock()
+
+	total := int64(len(matched))
+	dir := queryDir(params, cursor)
+
+	sort.Slice(matched, func(i, j int) bool {
+		c := compareItems(matched[i], matched[j], params.SortBy)
+		if dir == "asc" {
+			return c < 0
+		}
+		return c > 0
+	})
+
+	var window []*domain.Item
+	if cursor != nil {
+		anchor := &domain.Item{ID: cursor.ID}
+		if err := setSortValue(anchor, params.SortBy, cursor); err != nil {
+			return nil, err
+		}
+		start := sort.Search(len(matched), func(i int) bool {
+			c := compareItems(matched[i], anchor, params.SortBy)
+			if dir == "asc" {
+				return c > 0
+			}
+			return c < 0
+		})
+		window = matched[start:]
+	} else {
+		offset := (params.Page - 1) * params.PageSize
+		if offset < len(matched) {
+			window = matched[offset:]
+		}
+	}
+
+	if len(window) > params.PageSize+1 {
+		window = window[:params.PageSize+1]
+	}
+
+	return paginate(window, total, params, cursor), nil
+}
+
+func (r *MemoryStrategy) GetByID(ctx context.Context, scope domain.Scope, id string) (*domain.Item, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	item, ok := r.visible(scope, id)
+	if !ok {
+		return nil, fmt.Errorf("memory get by id error: %w", domain.ErrNotFound)
+	}
+	return copyItem(item), nil
+}
+
+func (r *MemoryStrategy) Update(ctx context.Context, scope domain.Scope, id string, patch domain.ItemPatch) (*domain.Item, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.visible(scope, id)
+	if !ok {
+		return nil, fmt.Errorf("memory update error: %w", domain.ErrNotFound)
+	}
+	if err := checkModify(scope, item); err != nil {
+		return nil, fmt.Errorf("memory update error: %w", err)
+	}
+	updated := copyItem(item)
+	if patch.Name != nil {
+		updated.Name = *patch.Name
+	}
+	if patch.Content != nil {
+		updated.Content = *patch.Content
+	}
+	updated.UpdatedAt = time.Now().UTC()
+
+	if err := r.appendOutbox(domain.EventItemUpdated, updated); err != nil {
+		return nil, err
+	}
+	r.items[id] = updated
+
+	return copyItem(updated), nil
+}
+
+func (r *MemoryStrategy) Upsert(ctx context.Context, scope domain.Scope, item *domain.Item) (*domain.Item, bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	row := &domain.Item{
+		ID:        item.ID,
+		Name:      item.Name,
+		Content:   item.Content,
+		OwnerID:   item.OwnerID,
+		TenantID:  item.TenantID,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	existing, found := r.items[item.ID]
+	eventType := domain.EventItemCreated
+	if found {
+		if err := checkUpsert(scope, existing); err != nil {
+			return nil, false, fmt.Errorf("memory upsert error: %w", err)
+		}
+		row.OwnerID, row.TenantID, row.CreatedAt = existing.OwnerID, existing.TenantID, existing.CreatedAt
+		eventType = domain.EventItemUpdated
+	}
+
+	if err := r.appendOutbox(eventType, row); err != nil {
+		return nil, false, err
+	}
+	r.items[item.ID] = row
+	return copyItem(row), !found, nil
+}
+
+func (r *MemoryStrategy) Delete(ctx context.Context, scope domain.Scope, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	item, ok := r.visible(scope, id)
+	if !ok {
+		return fmt.Errorf("memory delete error: %w", domain.ErrNotFound)
+	}
+	if err := checkModify(scope, item); err != nil {
+		return fmt.Errorf("memory delete error: %w", err)
+	}
+	if err := r.appendOutbox(domain.EventItemDeleted, deletedPayload(item)); err != nil {
+		return err
+	}
+	delete(r.items, id)
+	return nil
+}
+
+func (r *MemoryStrategy) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var events []*domain.OutboxEvent
+	for i := range r.outbox {
+		if len(events) >= limit {
+			break
+		}
+		if r.outbox[i].deliveredAt.IsZero() {
+			ev := r.outbox[i].event
+			events = append(events, &ev)
+		}
+	}
+	return events, nil
+}
+
+func (r *MemoryStrategy) ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*domain.OutboxEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	var events []*domain.OutboxEvent
+	for i := range r.outbox {
+		if len(events) >= limit {
+			break
+		}
+		row := &r.outbox[i]
+		if !row.deliveredAt.IsZero() || (row.claimedUntil.After(now) && row.claimedBy != owner) {
+			continue
+		}
+		row.claimedBy, row.claimedUntil = owner, now.Add(lease)
+		ev := row.event
+		events = append(events, &ev)
+	}
+	return events, nil
+}
+
+func (r *MemoryStrategy) MarkDelivered(ctx context.Context, ids []string) error {
+	delivered := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		delivered[id] = true
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now().UTC()
+	for i := range r.outbox {
+		if delivered[r.outbox[i].event.ID] && r.outbox[i].deliveredAt.IsZero() {
+			r.outbox[i].deliveredAt = now
+		}
+	}
+	return nil
+}
+
+func (r *MemoryStrategy) PurgeDelivered(ctx context.Context, before time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.outbox[:0]
+	for _, row := range r.outbox {
+		if row.deliveredAt.IsZero() || !row.deliveredAt.Before(before) {
+			kept = append(kept, row)
+		}
+	}
+	r.outbox = kept
+	return nil
+}
+
+func (r *MemoryStrategy) Ping(ctx context.Context) error {
+	return ctx.Err()
+}
+
+func (r *MemoryStrategy) Close(ctx context.Context) error {
+	return nil
+}
+
+// visible phải được gọi khi đang giữ r.mu.
+func (r *MemoryStrategy) visible(scope domain.Scope, id string) (*domain.Item, bool) {
+	item, ok := r.items[id]
+	if !ok || !scope.Allows(item.OwnerID, item.TenantID) {
+		return nil, false
+	}
+	return item, true
+}
+
+// appendOutbox phải được gọi khi đang giữ r.mu.
+func (r *MemoryStrategy) appendOutbox(eventType string, payload interface{}) error {
+	data, err := marshalOutboxPayload(payload)
+	if err != nil {
+		return err
+	}
+	r.nextID++
+	r.outbox = append(r.outbox, memoryOutboxEvent{event: domain.OutboxEvent{
+		ID:        strconv.FormatInt(r.nextID, 10),
+		Type:      eventType,
+		Payload:   json.RawMessage(data),
+		CreatedAt: time.Now().UTC(),
+	}})
+	return nil
+}
+
+func copyItem(item *domain.Item) *domain.Item {
+	c := *item
+	return &c
+}
+
+// matchesFilters mô phỏng full-text search đơn giản: mọi từ trong q phải
+// xuất hiện (không phân biệt hoa thường) trong name hoặc content.
+func matchesFilters(item *domain.Item, params domain.ListParams, terms []string) bool {
+	if !params.CreatedAfter.IsZero() && !item.CreatedAt.After(params.CreatedAfter) {
+		return false
+	}
+	if !params.CreatedBefore.IsZero() && !item.CreatedAt.Before(params.CreatedBefore) {
+		return false
+	}
+	if !params.UpdatedAfter.IsZero() && !item.UpdatedAt.After(params.UpdatedAfter) {
+		return false
+	}
+	if len(terms) == 0 {
+		return true
+	}
+
+	text := strings.ToLower(item.Name + " " + item.Content)
+	for _, term := range terms {
+		if !strings.Contains(text, term) {
+			return false
+		}
+	}
+	return true
+}
+
+// compareItems so sánh theo (field, id), cùng thứ tự với buildOrderClause.
+func compareItems(a, b *domain.Item, field string) int {
+	var c int
+	switch field {
+	case "created_at":
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	case "updated_at":
+		c = a.UpdatedAt.Compare(b.UpdatedAt)
+	case "name":
+		c = strings.Compare(a.Name, b.Name)
+	}
+	if c != 0 {
+		return c
+	}
+	return strings.Compare(a.ID, b.ID)
+}
+
+func setSortValue(item *domain.Item, field string, cursor *domain.Cursor) error {
+	value, err := cursorArg(cursor)
+	if err != nil {
+		return err
+	}
+	switch field {
+	case "created_at":
+		item.CreatedAt = value.(time.Time)
+	case "updated_at":
+		item.UpdatedAt = value.(time.Time)
+	case "name":
+		item.Name = value.(string)
+	}
+	return nil
+}
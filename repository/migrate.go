@@ -0,0 +1,187 @@
+package repository
+
+import (
+	"context"
+	"embed"
+	"fmt"
+	"io/fs"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+//go:embed migrations
+var migrationFiles embed.FS
+
+type MigrateMode string
+
+const (
+	// MigrateCheck từ chối khởi động nếu schema còn migration chưa chạy.
+	MigrateCheck MigrateMode = "check"
+	// MigrateAuto tự chạy các migration còn thiếu lúc khởi động (dev/local).
+	MigrateAuto MigrateMode = "auto"
+)
+
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+}
+
+type Migrator interface {
+	Status(ctx context.Context) ([]MigrationStatus, error)
+	// Up chạy mọi migration chưa áp dụng, trả về số migration đã chạy.
+	Up(ctx context.Context) (int, error)
+	// Down revert tối đa steps migration mới nhất, trả về số migration đã revert.
+	Down(ctx context.Context, steps int) (int, error)
+	// Close đóng kết nối database của Migrator. Migrator lấy từ strategy đang
+	// chạy dùng chung kết nối với strategy đó, chỉ Close Migrator từ NewMigrator.
+	Close() error
+}
+
+// migratable được implement bởi các strategy có schema cần quản lý.
+type migratable interface {
+	Migrator() Migrator
+}
+
+// NewMigrator mở kết nối theo cfg và trả về Migrator, không kiểm tra schema.
+// Dùng cho subcommand `migrate`.
+func NewMigrator(cfg DBConfig) (Migrator, error) {
+	repo, err := openRepository(cfg)
+	if err != nil {
+		return nil, err
+	}
+	m, ok := repo.(migratable)
+	if !ok {
+		repo.Close(context.Background())
+		return nil, fmt.Errorf("db type %s has no schema migrations", cfg.Type)
+	}
+	return m.Migrator(), nil
+}
+
+func ensureSchema(ctx context.Context, m Migrator, mode MigrateMode) error {
+	if mode == MigrateAuto {
+		_, err := m.Up(ctx)
+		return err
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		return err
+	}
+	var pending []string
+	for _, s := range status {
+		if !s.Applied {
+			pending = append(pending, fmt.Sprintf("%04d_%s", s.Version, s.Name))
+		}
+	}
+	if len(pending) > 0 {
+		return fmt.Errorf("schema is behind, pending migrations: %s (run `migrate up`)", strings.Join(pending, ", "))
+	}
+	return nil
+}
+
+type sqlMigration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+// loadSQLMigrations đọc migrations/<dialect>/NNNN_name.{up,down}.sql,
+// sắp xếp theo version tăng dần.
+func loadSQLMigrations(dialect string) ([]sqlMigration, error) {
+	dir := path.Join("migrations", dialect)
+	entries, err := fs.ReadDir(migrationFiles, dir)
+	if err != nil {
+		return nil, fmt.Errorf("read migrations %s: %w", dir, err)
+	}
+
+	byVersion := make(map[int]*sqlMigration)
+	for _, e := range entries {
+		file := e.Name()
+		base, direction, ok := cutDirection(file)
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+		prefix, name, ok := strings.Cut(base, "_")
+		if !ok {
+			return nil, fmt.Errorf("invalid migration file name %s", file)
+		}
+		version, err := strconv.Atoi(prefix)
+		if err != nil {
+			return nil, fmt.Errorf("invalid migration version %s: %w", file, err)
+		}
+
+		body, err := fs.ReadFile(migrationFiles, path.Join(dir, file))
+		if err != nil {
+			return nil, err
+		}
+
+		m, ok := byVersion[version]
+		if !ok {
+			m = &sqlMigration{Version: version, Name: name}
+			byVersion[version] = m
+		}
+		if direction == "up" {
+			m.Up = string(body)
+		} else {
+			m.Down = string(body)
+		}
+	}
+
+	migrations := make([]sqlMigration, 0, len(byVersion))
+	for _, m := range byVersion {
+		if m.Up == "" || m.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s must have both up and down files", m.Version, m.Name)
+		}
+		migrations = append(migrations, *m)
+	}
+	sort.Slice(migrations, func(i, j int) bool { return migrations[i].Version < migrations[j].Version })
+	return migrations, nil
+}
+
+func cutDirection(file string) (base, direction string, ok bool) {
+	if base, ok = strings.CutSuffix(file, ".up.sql"); ok {
+		return base, "up", true
+	}
+	if base, ok = strings.CutSuffix(file, ".down.sql"); ok {
+		return base, "down", true
+	}
+	return "", "", false
+}
+
+// splitStatements tách file migration thành từng câu lệnh, vì driver MySQL
+// mặc định không cho nhiều câu lệnh trong một Exec. Dấu ; nằm trong thân
+// function $$...$$ của Postgres không được tính là kết thúc câu lệnh.
+func splitStatements(sql string) []string {
+	var stmts []string
+	var cur strings.Builder
+	flush := func() {
+		if s := strings.TrimSpace(cur.String()); s != "" {
+			stmts = append(stmts, s)
+		}
+		cur.Reset()
+	}
+
+	for i, part := range strings.Split(sql, "$$") {
+		if i > 0 {
+			cur.WriteString("$$")
+		}
+		if i%2 == 1 {
+			cur.WriteString(part)
+			continue
+		}
+		for j, piece := range strings.Split(part, ";") {
+			if j > 0 {
+				flush()
+			}
+			cur.WriteString(piece)
+		}
+	}
+	flush()
+	return stmts
+}
@@ -0,0 +1,117 @@
+package repository
+
+import (
+	"context"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestLoadSQLMigrations(t *testing.T) {
+	for _, dialect := range []string{"postgres", "mysql", "sqlite"} {
+		migrations, err := loadSQLMigrations(dialect)
+		if err != nil {
+			t.Fatalf("%s: %v", dialect, err)
+		}
+		for i, m := range migrations {
+			if m.Version != i+1 {
+				t.Fatalf("%s: migration #%d has version %d, want contiguous versions from 1", dialect, i, m.Version)
+			}
+		}
+	}
+}
+
+func TestGormMigratorUpDown(t *testing.T) {
+	ctx := context.Background()
+	repo, err := NewSQLiteStrategy(":memory:")
+	if err != nil {
+		t.Fatalf("sqlite: %v", err)
+	}
+	m := repo.Migrator()
+
+	if err := ensureSchema(ctx, m, MigrateCheck); err == nil {
+		t.Fatal("check on empty schema: want error")
+	}
+
+	applied, err := m.Up(ctx)
+	if err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if applied == 0 {
+		t.Fatal("up: no migrations applied")
+	}
+	if again, err := m.Up(ctx); err != nil || again != 0 {
+		t.Fatalf("second up: applied %d, err %v", again, err)
+	}
+	if err := ensureSchema(ctx, m, MigrateCheck); err != nil {
+		t.Fatalf("check after up: %v", err)
+	}
+
+	reverted, err := m.Down(ctx, applied)
+	if err != nil || reverted != applied {
+		t.Fatalf("down: reverted %d/%d, err %v", reverted, applied, err)
+	}
+	if repo.db.Migrator().HasTable("items") {
+		t.Fatal("down: items table still exists")
+	}
+
+	status, err := m.Status(ctx)
+	if err != nil {
+		t.Fatalf("status: %v", err)
+	}
+	for _, s := range status {
+		if s.Applied {
+			t.Fatalf("status after down: %04d_%s still applied", s.Version, s.Name)
+		}
+	}
+
+	if err := ensureSchema(ctx, m, MigrateAuto); err != nil {
+		t.Fatalf("auto: %v", err)
+	}
+	if err := ensureSchema(ctx, m, MigrateCheck); err != nil {
+		t.Fatalf("check after auto: %v", err)
+	}
+}
+
+func TestNewMigratorCloseReleasesTenantDatabases(t *testing.T) {
+	dir := t.TempDir()
+	m, err := NewMigrator(DBConfig{
+		Type:       DBTypeSQLite,
+		DSN:        filepath.Join(dir, "shared.db"),
+		TenantDSNs: map[string]string{"acme": filepath.Join(dir, "acme.db")},
+	})
+	if err != nil {
+		t.Fatalf("new migrator: %v", err)
+	}
+	if _, err := m.Up(context.Background()); err != nil {
+		t.Fatalf("up: %v", err)
+	}
+	if err := m.Close(); err != nil {
+		t.Fatalf("close: %v", err)
+	}
+	// cả database chung và database của tenant đều đã đóng
+	for _, mg := range m.(*tenantMigrator).migrators {
+		if _, err := mg.Up(context.Background()); err == nil {
+			t.Fatal("up after close: want error")
+		}
+	}
+}
+
+func TestSplitStatementsKeepsDollarQuotedBodies(t *testing.T) {
+	sql := `CREATE FUNCTION f() RETURNS trigger AS $$
+BEGIN
+    PERFORM 1;
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TABLE x;
+`
+	stmts := splitStatements(sql)
+	if len(stmts) != 2 {
+		t.Fatalf("got %d statements, want 2: %q", len(stmts), stmts)
+	}
+	if !strings.HasSuffix(stmts[0], "$$ LANGUAGE plpgsql") || stmts[1] != "DROP TABLE x" {
+		t.Fatalf("unexpected statements: %q", stmts)
+	}
+}
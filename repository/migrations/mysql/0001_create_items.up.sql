@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS items (
+    id         VARCHAR(36)  NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    content    TEXT,
+    created_at DATETIME(3)  NULL,
+    updated_at DATETIME(3)  NULL,
+    PRIMARY KEY (id),
+    INDEX idx_items_created_at (created_at)
+);
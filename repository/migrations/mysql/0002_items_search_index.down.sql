@@ -0,0 +1 @@
+DROP INDEX ft_items_search ON items;
@@ -0,0 +1 @@
+CREATE FULLTEXT INDEX ft_items_search ON items (name, content);
@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id           BIGINT      NOT NULL AUTO_INCREMENT,
+    event_type   VARCHAR(64) NOT NULL,
+    payload      MEDIUMTEXT  NOT NULL,
+    created_at   DATETIME(3) NOT NULL,
+    delivered_at DATETIME(3) NULL,
+    PRIMARY KEY (id),
+    INDEX idx_outbox_delivered_at (delivered_at)
+);
@@ -0,0 +1,3 @@
+DROP TRIGGER IF EXISTS items_after_delete;
+DROP TABLE IF EXISTS item_tombstones;
+DROP INDEX idx_items_updated_at ON items;
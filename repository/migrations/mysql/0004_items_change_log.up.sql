@@ -0,0 +1,13 @@
+CREATE INDEX idx_items_updated_at ON items (updated_at, id);
+
+CREATE TABLE IF NOT EXISTS item_tombstones (
+    seq        BIGINT       NOT NULL AUTO_INCREMENT,
+    id         VARCHAR(36)  NOT NULL,
+    name       VARCHAR(255) NOT NULL,
+    deleted_at DATETIME(3)  NOT NULL,
+    PRIMARY KEY (seq),
+    INDEX idx_item_tombstones_deleted_at (deleted_at)
+);
+
+CREATE TRIGGER items_after_delete AFTER DELETE ON items
+    FOR EACH ROW INSERT INTO item_tombstones (id, name, deleted_at) VALUES (OLD.id, OLD.name, NOW(3));
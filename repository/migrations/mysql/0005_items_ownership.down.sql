@@ -0,0 +1,14 @@
+DROP TRIGGER IF EXISTS items_after_delete;
+
+CREATE TRIGGER items_after_delete AFTER DELETE ON items
+    FOR EACH ROW INSERT INTO item_tombstones (id, name, deleted_at) VALUES (OLD.id, OLD.name, NOW(3));
+
+ALTER TABLE item_tombstones
+    DROP COLUMN tenant_id,
+    DROP COLUMN owner_id;
+
+ALTER TABLE items
+    DROP INDEX idx_items_tenant_id,
+    DROP INDEX idx_items_owner_id,
+    DROP COLUMN tenant_id,
+    DROP COLUMN owner_id;
@@ -0,0 +1,15 @@
+ALTER TABLE items
+    ADD COLUMN owner_id  VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '',
+    ADD INDEX idx_items_owner_id (owner_id),
+    ADD INDEX idx_items_tenant_id (tenant_id);
+
+ALTER TABLE item_tombstones
+    ADD COLUMN owner_id  VARCHAR(255) NOT NULL DEFAULT '',
+    ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '';
+
+DROP TRIGGER IF EXISTS items_after_delete;
+
+CREATE TRIGGER items_after_delete AFTER DELETE ON items
+    FOR EACH ROW INSERT INTO item_tombstones (id, name, owner_id, tenant_id, deleted_at)
+    VALUES (OLD.id, OLD.name, OLD.owner_id, OLD.tenant_id, NOW(3));
@@ -0,0 +1,3 @@
+ALTER TABLE outbox
+    ADD COLUMN claimed_by    VARCHAR(64) NULL,
+    ADD COLUMN claimed_until DATETIME(3) NULL;
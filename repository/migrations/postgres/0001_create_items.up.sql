@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS items (
+    id         VARCHAR(36)  PRIMARY KEY,
+    name       VARCHAR(255) NOT NULL,
+    content    TEXT,
+    created_at TIMESTAMPTZ,
+    updated_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_items_created_at ON items (created_at);
@@ -0,0 +1,2 @@
+CREATE INDEX IF NOT EXISTS idx_items_search ON items
+    USING GIN (to_tsvector('simple', coalesce(name, '') || ' ' || coalesce(content, '')));
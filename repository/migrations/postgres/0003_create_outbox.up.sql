@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id           BIGSERIAL   PRIMARY KEY,
+    event_type   VARCHAR(64) NOT NULL,
+    payload      TEXT        NOT NULL,
+    created_at   TIMESTAMPTZ NOT NULL,
+    delivered_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_pending ON outbox (id) WHERE delivered_at IS NULL;
@@ -0,0 +1,2 @@
+DROP TRIGGER IF EXISTS items_change_notify ON items;
+DROP FUNCTION IF EXISTS notify_items_change();
@@ -0,0 +1,19 @@
+CREATE OR REPLACE FUNCTION notify_items_change() RETURNS trigger AS $$
+DECLARE
+    changed items%ROWTYPE;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+    PERFORM pg_notify('items_changes', json_build_object('op', TG_OP, 'id', changed.id, 'name', changed.name)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS items_change_notify ON items;
+
+CREATE TRIGGER items_change_notify
+    AFTER INSERT OR UPDATE OR DELETE ON items
+    FOR EACH ROW EXECUTE FUNCTION notify_items_change();
@@ -0,0 +1,19 @@
+CREATE OR REPLACE FUNCTION notify_items_change() RETURNS trigger AS $$
+DECLARE
+    changed items%ROWTYPE;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+    PERFORM pg_notify('items_changes', json_build_object('op', TG_OP, 'id', changed.id, 'name', changed.name)::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
+
+DROP INDEX IF EXISTS idx_items_tenant_id;
+DROP INDEX IF EXISTS idx_items_owner_id;
+
+ALTER TABLE items DROP COLUMN IF EXISTS tenant_id;
+ALTER TABLE items DROP COLUMN IF EXISTS owner_id;
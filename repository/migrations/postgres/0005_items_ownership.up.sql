@@ -0,0 +1,25 @@
+ALTER TABLE items ADD COLUMN IF NOT EXISTS owner_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN IF NOT EXISTS tenant_id VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_items_owner_id ON items (owner_id);
+CREATE INDEX IF NOT EXISTS idx_items_tenant_id ON items (tenant_id);
+
+CREATE OR REPLACE FUNCTION notify_items_change() RETURNS trigger AS $$
+DECLARE
+    changed items%ROWTYPE;
+BEGIN
+    IF TG_OP = 'DELETE' THEN
+        changed := OLD;
+    ELSE
+        changed := NEW;
+    END IF;
+    PERFORM pg_notify('items_changes', json_build_object(
+        'op', TG_OP,
+        'id', changed.id,
+        'name', changed.name,
+        'owner_id', changed.owner_id,
+        'tenant_id', changed.tenant_id
+    )::text);
+    RETURN NULL;
+END;
+$$ LANGUAGE plpgsql;
@@ -0,0 +1,3 @@
+ALTER TABLE outbox
+    ADD COLUMN IF NOT EXISTS claimed_by    VARCHAR(64),
+    ADD COLUMN IF NOT EXISTS claimed_until TIMESTAMPTZ;
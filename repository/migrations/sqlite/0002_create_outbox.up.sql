@@ -0,0 +1,9 @@
+CREATE TABLE IF NOT EXISTS outbox (
+    id           INTEGER     PRIMARY KEY AUTOINCREMENT,
+    event_type   VARCHAR(64) NOT NULL,
+    payload      TEXT        NOT NULL,
+    created_at   DATETIME    NOT NULL,
+    delivered_at DATETIME
+);
+
+CREATE INDEX IF NOT EXISTS idx_outbox_delivered_at ON outbox (delivered_at);
@@ -0,0 +1,5 @@
+DROP INDEX IF EXISTS idx_items_tenant_id;
+DROP INDEX IF EXISTS idx_items_owner_id;
+
+ALTER TABLE items DROP COLUMN tenant_id;
+ALTER TABLE items DROP COLUMN owner_id;
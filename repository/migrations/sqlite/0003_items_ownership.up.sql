@@ -0,0 +1,5 @@
+ALTER TABLE items ADD COLUMN owner_id VARCHAR(255) NOT NULL DEFAULT '';
+ALTER TABLE items ADD COLUMN tenant_id VARCHAR(255) NOT NULL DEFAULT '';
+
+CREATE INDEX IF NOT EXISTS idx_items_owner_id ON items (owner_id);
+CREATE INDEX IF NOT EXISTS idx_items_tenant_id ON items (tenant_id);
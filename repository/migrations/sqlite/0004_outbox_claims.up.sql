@@ -0,0 +1,2 @@
+ALTER TABLE outbox ADD COLUMN claimed_by VARCHAR(64);
+ALTER TABLE outbox ADD COLUMN claimed_until DATETIME;
@@ -0,0 +1,116 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+type mongoChange struct {
+	OperationType string `bson:"operationType"`
+	DocumentKey   struct {
+		ID string `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             *domain.Item `bson:"fullDocument"`
+	FullDocumentBeforeChange *domain.Item `bson:"fullDocumentBeforeChange"`
+}
+
+// Watch dùng change stream trên collection items. Pre-image (migration v4)
+// cho phép ITEM_DELETED có name; khi bị lỗi stream được mở lại từ resume token
+// cuối cùng nên không mất event.
+func (r *MongoDBStrategy) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	stream, err := r.openChangeStream(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb watch error: %w", translateMongoError(err))
+	}
+
+	out := make(chan domain.ChangeEvent, changeFeedBuffer)
+	go func() {
+		defer close(out)
+		for {
+			token, err := r.consumeChangeStream(ctx, stream, out)
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[ChangeFeed] mongodb change stream error: %v, retrying in %s", err, changeFeedRetry)
+
+			for {
+				if !sleepCtx(ctx, changeFeedRetry) {
+					return
+				}
+				if stream, err = r.openChangeStream(ctx, token); err == nil {
+					break
+				}
+				log.Printf("[ChangeFeed] mongodb reopen change stream error: %v", err)
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (r *MongoDBStrategy) openChangeStream(ctx context.Context, resumeAfter bson.Raw) (*mongo.ChangeStream, error) {
+	pipeline := mongo.Pipeline{
+		{{Key: "$match", Value: bson.M{"operationType": bson.M{"$in": bson.A{"insert", "update", "replace", "delete"}}}}},
+	}
+	opts := options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+	if resumeAfter != nil {
+		opts.SetResumeAfter(resumeAfter)
+	}
+	return r.collection.Watch(ctx, pipeline, opts)
+}
+
+// consumeChangeStream đọc tới khi stream lỗi hoặc ctx bị huỷ, trả về resume
+// token của event cuối cùng đã gửi đi và lỗi của stream.
+func (r *MongoDBStrategy) consumeChangeStream(ctx context.Context, stream *mongo.ChangeStream, out chan<- domain.ChangeEvent) (bson.Raw, error) {
+	defer stream.Close(context.Background())
+
+	token := stream.ResumeToken()
+	for stream.Next(ctx) {
+		var change mongoChange
+		if err := stream.Decode(&change); err != nil {
+			log.Printf("[ChangeFeed] mongodb decode change error: %v", err)
+			token = stream.ResumeToken()
+			continue
+		}
+
+		ev, ok, err := mongoChangeEvent(change)
+		if err != nil {
+			log.Printf("[ChangeFeed] mongodb change %s: %v", change.DocumentKey.ID, err)
+		}
+		if ok && !emit(ctx, out, ev) {
+			return token, ctx.Err()
+		}
+		token = stream.ResumeToken()
+	}
+	return token, stream.Err()
+}
+
+func mongoChangeEvent(change mongoChange) (domain.ChangeEvent, bool, error) {
+	switch change.OperationType {
+	case "insert":
+		ev, err := newChangeEvent(domain.EventItemCreated, change.FullDocument)
+		return ev, err == nil, err
+	case "update", "replace":
+		// item đã bị xoá trước khi lookup, event delete sẽ theo sau
+		if change.FullDocument == nil {
+			return domain.ChangeEvent{}, false, nil
+		}
+		ev, err := newChangeEvent(domain.EventItemUpdated, change.FullDocument)
+		return ev, err == nil, err
+	case "delete":
+		before := change.FullDocumentBeforeChange
+		if before == nil {
+			before = &domain.Item{ID: change.DocumentKey.ID}
+		}
+		ev, err := newChangeEvent(domain.EventItemDeleted, deletedPayload(before))
+		return ev, err == nil, err
+	}
+	return domain.ChangeEvent{}, false, nil
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const mongoMigrationsCollection = "schema_migrations"
+
+type mongoMigration struct {
+	Version int
+	Name    string
+	Up      func(ctx context.Context, coll *mongo.Collection) error
+	Down    func(ctx context.Context, coll *mongo.Collection) error
+}
+
+// Tên index giữ đúng tên mặc định mongo sinh ra để nhận các index đã tạo
+// trước khi có migration.
+var mongoMigrations = []mongoMigration{
+	{
+		Version: 1,
+		Name:    "create_items",
+		Up: func(ctx context.Context, coll *mongo.Collection) error {
+			_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "created_at", Value: -1}},
+				Options: options.Index().SetName("created_at_-1"),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, coll *mongo.Collection) error {
+			return coll.Drop(ctx)
+		},
+	},
+	{
+		Version: 2,
+		Name:    "items_search_index",
+		Up: func(ctx context.Context, coll *mongo.Collection) error {
+			_, err := coll.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "name", Value: "text"}, {Key: "content", Value: "text"}},
+				Options: options.Index().SetName("name_text_content_text"),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, coll *mongo.Collection) error {
+			_, err := coll.Indexes().DropOne(ctx, "name_text_content_text")
+			return err
+		},
+	},
+	{
+		Version: 3,
+		Name:    "create_outbox",
+		Up: func(ctx context.Context, coll *mongo.Collection) error {
+			outbox := coll.Database().Collection(mongoOutboxCollection)
+			_, err := outbox.Indexes().CreateOne(ctx, mongo.IndexModel{
+				Keys:    bson.D{{Key: "delivered_at", Value: 1}, {Key: "_id", Value: 1}},
+				Options: options.Index().SetName("delivered_at_1__id_1"),
+			})
+			return err
+		},
+		Down: func(ctx context.Context, coll *mongo.Collection) error {
+			return coll.Database().Collection(mongoOutboxCollection).Drop(ctx)
+		},
+	},
+	{
+		// pre-image để change stream có name của item đã xoá (MongoDB 6+)
+		Version: 4,
+		Name:    "items_change_stream_pre_images",
+		Up: func(ctx context.Context, coll *mongo.Collection) error {
+			return setChangeStreamPreImages(ctx, coll, true)
+		},
+		Down: func(ctx context.Context, coll *mongo.Collection) error {
+			return setChangeStreamPreImages(ctx, coll, false)
+		},
+	},
+	{
+		Version: 5,
+		Name:    "items_ownership",
+		Up: func(ctx context.Context, coll *mongo.Collection) error {
+			_, err := coll.Indexes().CreateMany(ctx, []mongo.IndexModel{
+				{Keys: bson.D{{Key: "owner_id", Value: 1}}, Options: options.Index().SetName("owner_id_1")},
+				{Keys: bson.D{{Key: "tenant_id", Value: 1}}, Options: options.Index().SetName("tenant_id_1")},
+			})
+			return err
+		},
+		Down: func(ctx context.Context, coll *mongo.Collection) error {
+			for _, name := range []string{"tenant_id_1", "owner_id_1"} {
+				if _, err := coll.Indexes().DropOne(ctx, name); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	},
+}
+
+func setChangeStreamPreImages(ctx context.Context, coll *mongo.Collection, enabled bool) error {
+	return coll.Database().RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: coll.Name()},
+		{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": enabled}},
+	}).Err()
+}
+
+type mongoSchemaMigration struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+type mongoMigrator struct {
+	coll       *mongo.Collection
+	migrations *mongo.Collection
+}
+
+func (r *MongoDBStrategy) Migrator() Migrator {
+	return &mongoMigrator{
+		coll:       r.collection,
+		migrations: r.collection.Database().Collection(mongoMigrationsCollection),
+	}
+}
+
+func (m *mongoMigrator) Close() error {
+	if err := m.coll.Database().Client().Disconnect(context.Background()); err != nil {
+		return fmt.Errorf("mongodb close error: %w", translateMongoError(err))
+	}
+	return nil
+}
+
+func (m *mongoMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, len(mongoMigrations))
+	for i, mig := range mongoMigrations {
+		status[i] = MigrationStatus{Version: mig.Version, Name: mig.Name}
+		if row, ok := applied[mig.Version]; ok {
+			status[i].Applied = true
+			status[i].AppliedAt = row.AppliedAt
+		}
+	}
+	return status, nil
+}
+
+func (m *mongoMigrator) Up(ctx context.Context) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, mig := range mongoMigrations {
+		if _, ok := applied[mig.Version]; ok {
+			continue
+		}
+		if err := mig.Up(ctx, m.coll); err != nil {
+			return count, fmt.Errorf("mongodb migrate up %04d_%s error: %w", mig.Version, mig.Name, translateMongoError(err))
+		}
+		row := mongoSchemaMigration{Version: mig.Version, Name: mig.Name, AppliedAt: time.Now().UTC()}
+		if _, err := m.migrations.InsertOne(ctx, row); err != nil {
+			return count, fmt.Errorf("mongodb migrate up %04d_%s error: %w", mig.Version, mig.Name, translateMongoError(err))
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (m *mongoMigrator) Down(ctx context.Context, steps int) (int, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(mongoMigrations) - 1; i >= 0 && count < steps; i-- {
+		mig := mongoMigrations[i]
+		if _, ok := applied[mig.Version]; !ok {
+			continue
+		}
+		if err := mig.Down(ctx, m.coll); err != nil {
+			return count, fmt.Errorf("mongodb migrate down %04d_%s error: %w", mig.Version, mig.Name, translateMongoError(err))
+		}
+		if _, err := m.migrations.DeleteOne(ctx, bson.M{"_id": mig.Version}); err != nil {
+			return count, fmt.Errorf("mongodb migrate down %04d_%s error: %w", mig.Version, mig.Name, translateMongoError(err))
+		}
+		count++
+	}
+	return count, nil
+}
+
+func (m *mongoMigrator) applied(ctx context.Context) (map[int]mongoSchemaMigration, error) {
+	cur, err := m.migrations.Find(ctx, bson.D{})
+	if err != nil {
+		return nil, fmt.Errorf("mongodb schema_migrations error: %w", translateMongoError(err))
+	}
+	defer cur.Close(ctx)
+
+	var rows []mongoSchemaMigration
+	if err := cur.All(ctx, &rows); err != nil {
+		return nil, fmt.Errorf("mongodb schema_migrations error: %w", translateMongoError(err))
+	}
+
+	applied := make(map[int]mongoSchemaMigration, len(rows))
+	for _, row := range rows {
+		applied[row.Version] = row
+	}
+	return applied, nil
+}
@@ -0,0 +1,174 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const mongoOutboxCollection = "outbox"
+
+type mongoOutboxEvent struct {
+	ID          primitive.ObjectID `bson:"_id"`
+	EventType   string             `bson:"event_type"`
+	Payload     string             `bson:"payload"`
+	CreatedAt   time.Time          `bson:"created_at"`
+	DeliveredAt *time.Time         `bson:"delivered_at"`
+	// relay đang giữ event và hạn lease, xem ClaimPending
+	ClaimedBy    string     `bson:"claimed_by,omitempty"`
+	ClaimedUntil *time.Time `bson:"claimed_until,omitempty"`
+}
+
+func (r *MongoDBStrategy) withTransaction(ctx context.Context, fn func(sc mongo.SessionContext) error) error {
+	session, err := r.collection.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer session.EndSession(ctx)
+
+	_, err = session.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		return nil, fn(sc)
+	})
+	return err
+}
+
+func (r *MongoDBStrategy) insertOutbox(ctx context.Context, eventType string, payload interface{}) error {
+	data, err := marshalOutboxPayload(payload)
+	if err != nil {
+		return err
+	}
+	_, err = r.outbox.InsertOne(ctx, &mongoOutboxEvent{
+		ID:        primitive.NewObjectID(),
+		EventType: eventType,
+		Payload:   data,
+		CreatedAt: time.Now(),
+	})
+	return err
+}
+
+func (r *MongoDBStrategy) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit))
+
+	cur, err := r.outbox.Find(ctx, bson.M{"delivered_at": nil}, opts)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb outbox fetch error: %w", translateMongoError(err))
+	}
+	defer cur.Close(ctx)
+
+	var docs []mongoOutboxEvent
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("mongodb outbox decode error: %w", translateMongoError(err))
+	}
+	return mongoOutboxEvents(docs), nil
+}
+
+// mongoClaimable: event chưa giao, chưa ai giữ, lease đã hết hạn hoặc chính
+// owner đang giữ.
+func mongoClaimable(owner string, now time.Time) bson.M {
+	return bson.M{
+		"delivered_at": nil,
+		"$or": bson.A{
+			bson.M{"claimed_until": nil},
+			bson.M{"claimed_until": bson.M{"$lt": now}},
+			bson.M{"claimed_by": owner},
+		},
+	}
+}
+
+func (r *MongoDBStrategy) ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*domain.OutboxEvent, error) {
+	now := time.Now()
+	opts := options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetLimit(int64(limit)).
+		SetProjection(bson.M{"_id": 1})
+
+	cur, err := r.outbox.Find(ctx, mongoClaimable(owner, now), opts)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb outbox claim error: %w", translateMongoError(err))
+	}
+	var candidates []mongoOutboxEvent
+	if err := cur.All(ctx, &candidates); err != nil {
+		return nil, fmt.Errorf("mongodb outbox decode error: %w", translateMongoError(err))
+	}
+	if len(candidates) == 0 {
+		return nil, nil
+	}
+	ids := make([]primitive.ObjectID, len(candidates))
+	for i, doc := range candidates {
+		ids[i] = doc.ID
+	}
+
+	// update từng document là atomic và điều kiện được kiểm tra lại, nên hai
+	// relay chọn trùng event thì chỉ một bên claim được
+	filter := mongoClaimable(owner, now)
+	filter["_id"] = bson.M{"$in": ids}
+	_, err = r.outbox.UpdateMany(ctx, filter, bson.M{"$set": bson.M{"claimed_by": owner, "claimed_until": now.Add(lease)}})
+	if err != nil {
+		return nil, fmt.Errorf("mongodb outbox claim error: %w", translateMongoError(err))
+	}
+
+	cur, err = r.outbox.Find(ctx,
+		bson.M{"_id": bson.M{"$in": ids}, "claimed_by": owner, "delivered_at": nil},
+		options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("mongodb outbox claim error: %w", translateMongoError(err))
+	}
+	var docs []mongoOutboxEvent
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, fmt.Errorf("mongodb outbox decode error: %w", translateMongoError(err))
+	}
+	return mongoOutboxEvents(docs), nil
+}
+
+func mongoOutboxEvents(docs []mongoOutboxEvent) []*domain.OutboxEvent {
+	events := make([]*domain.OutboxEvent, len(docs))
+	for i, doc := range docs {
+		events[i] = &domain.OutboxEvent{
+			ID:        doc.ID.Hex(),
+			Type:      doc.EventType,
+			Payload:   []byte(doc.Payload),
+			CreatedAt: doc.CreatedAt,
+		}
+	}
+	return events
+}
+
+func (r *MongoDBStrategy) MarkDelivered(ctx context.Context, ids []string) error {
+	if len(ids) == 0 {
+		return nil
+	}
+	keys := make([]primitive.ObjectID, 0, len(ids))
+	for _, id := range ids {
+		key, err := primitive.ObjectIDFromHex(id)
+		if err != nil {
+			return fmt.Errorf("mongodb outbox mark error: %w", err)
+		}
+		keys = append(keys, key)
+	}
+
+	_, err := r.outbox.UpdateMany(ctx,
+		bson.M{"_id": bson.M{"$in": keys}},
+		bson.M{"$set": bson.M{"delivered_at": time.Now()}},
+	)
+	if err != nil {
+		return fmt.Errorf("mongodb outbox mark error: %w", translateMongoError(err))
+	}
+	return nil
+}
+
+func (r *MongoDBStrategy) PurgeDelivered(ctx context.Context, before time.Time) error {
+	_, err := r.outbox.DeleteMany(ctx, bson.M{"delivered_at": bson.M{"$ne": nil, "$lt": before}})
+	if err != nil {
+		return fmt.Errorf("mongodb outbox purge error: %w", translateMongoError(err))
+	}
+	return nil
+}
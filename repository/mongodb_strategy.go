@@ -2,8 +2,8 @@ package repository
 
 import (
 	"context"
+	"errors"
 	"fmt"
-	"math"
 	"time"
 
 	"github.com/JIeeiroSst/hub/domain"
@@ -13,33 +13,33 @@ import (
 	"go.mongodb.org/mongo-driver/mongo/options"
 )
 
+// MongoDBStrategy ghi item và outbox trong cùng một transaction nên MongoDB
+// phải chạy dạng replica set (một node cũng được).
 type MongoDBStrategy struct {
 	collection *mongo.Collection
+	outbox     *mongo.Collection
 }
 
-func NewMongoDBStrategy(uri, dbName, collectionName string) (*MongoDBStrategy, error) {
+// opts được áp dụng sau uri, ví dụ để chỉnh connection pool.
+func NewMongoDBStrategy(uri, dbName, collectionName string, opts ...*options.ClientOptions) (*MongoDBStrategy, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	client, err := mongo.Connect(ctx, options.Client().ApplyURI(uri))
+	client, err := mongo.Connect(ctx, append([]*options.ClientOptions{options.Client().ApplyURI(uri)}, opts...)...)
 	if err != nil {
-		return nil, fmt.Errorf("mongodb connect error: %w", err)
+		return nil, fmt.Errorf("mongodb connect error: %w", translateMongoError(err))
 	}
 
 	if err := client.Ping(ctx, nil); err != nil {
-		return nil, fmt.Errorf("mongodb ping error: %w", err)
+		return nil, fmt.Errorf("mongodb ping error: %w", translateMongoError(err))
 	}
 
-	collection := client.Database(dbName).Collection(collectionName)
+	db := client.Database(dbName)
 
-	_, err = collection.Indexes().CreateOne(ctx, mongo.IndexModel{
-		Keys: bson.D{{Key: "created_at", Value: -1}},
-	})
-	if err != nil {
-		return nil, fmt.Errorf("mongodb create index error: %w", err)
-	}
-
-	return &MongoDBStrategy{collection: collection}, nil
+	return &MongoDBStrategy{
+		collection: db.Collection(collectionName),
+		outbox:     db.Collection(mongoOutboxCollection),
+	}, nil
 }
 
 func (r *MongoDBStrategy) Create(ctx context.Context, item *domain.Item) (*domain.Item, error) {
@@ -48,67 +48,221 @@ func (r *MongoDBStrategy) Create(ctx context.Context, item *domain.Item) (*domai
 		ID:        uuid.NewString(),
 		Name:      item.Name,
 		Content:   item.Content,
+		OwnerID:   item.OwnerID,
+		TenantID:  item.TenantID,
 		CreatedAt: now,
 		UpdatedAt: now,
 	}
 
-	if _, err := r.collection.InsertOne(ctx, doc); err != nil {
-		return nil, fmt.Errorf("mongodb create error: %w", err)
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if _, err := r.collection.InsertOne(sc, doc); err != nil {
+			return err
+		}
+		return r.insertOutbox(sc, domain.EventItemCreated, doc)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mongodb create error: %w", translateMongoError(err))
 	}
 	return doc, nil
 }
 
-func (r *MongoDBStrategy) List(ctx context.Context, params domain.ListParams) (*domain.ListResult, error) {
+func (r *MongoDBStrategy) List(ctx context.Context, scope domain.Scope, params domain.ListParams) (*domain.ListResult, error) {
 	params.SetDefaults()
 
-	filter := bson.D{}
+	cursor, err := resolveCursor(&params)
+	if err != nil {
+		return nil, err
+	}
+
+	filter := buildMongoFilter(scope, params)
 
 	total, err := r.collection.CountDocuments(ctx, filter)
 	if err != nil {
-		return nil, fmt.Errorf("mongodb count error: %w", err)
+		return nil, fmt.Errorf("mongodb count error: %w", translateMongoError(err))
 	}
 
 	sortVal := -1
-	if params.SortDir == "asc" {
+	if queryDir(params, cursor) == "asc" {
 		sortVal = 1
 	}
 
-	skip := int64((params.Page - 1) * params.PageSize)
-	limit := int64(params.PageSize)
+	field := mongoField(params.SortBy)
+	sort := bson.D{{Key: field, Value: sortVal}}
+	if field != "_id" {
+		sort = append(sort, bson.E{Key: "_id", Value: sortVal})
+	}
 
 	opts := options.Find().
-		SetSort(bson.D{{Key: params.SortBy, Value: sortVal}}).
-		SetSkip(skip).
-		SetLimit(limit)
+		SetSort(sort).
+		SetLimit(int64(params.PageSize + 1))
 
-	cursor, err := r.collection.Find(ctx, filter, opts)
+	if cursor != nil {
+		keyset, err := buildMongoKeysetFilter(params, cursor, sortVal)
+		if err != nil {
+			return nil, err
+		}
+		filter = append(filter, keyset...)
+	} else {
+		opts.SetSkip(int64((params.Page - 1) * params.PageSize))
+	}
+
+	cur, err := r.collection.Find(ctx, filter, opts)
 	if err != nil {
-		return nil, fmt.Errorf("mongodb find error: %w", err)
+		return nil, fmt.Errorf("mongodb find error: %w", translateMongoError(err))
 	}
-	defer cursor.Close(ctx)
+	defer cur.Close(ctx)
 
 	var items []*domain.Item
-	if err := cursor.All(ctx, &items); err != nil {
-		return nil, fmt.Errorf("mongodb decode error: %w", err)
+	if err := cur.All(ctx, &items); err != nil {
+		return nil, fmt.Errorf("mongodb decode error: %w", translateMongoError(err))
 	}
 
-	return &domain.ListResult{
-		Items:      items,
-		Total:      total,
-		Page:       params.Page,
-		PageSize:   params.PageSize,
-		TotalPages: int(math.Ceil(float64(total) / float64(params.PageSize))),
-	}, nil
+	return paginate(items, total, params, cursor), nil
 }
 
-func (r *MongoDBStrategy) GetByID(ctx context.Context, id string) (*domain.Item, error) {
+func (r *MongoDBStrategy) GetByID(ctx context.Context, scope domain.Scope, id string) (*domain.Item, error) {
 	var item domain.Item
-	if err := r.collection.FindOne(ctx, bson.M{"_id": id}).Decode(&item); err != nil {
-		return nil, fmt.Errorf("mongodb get by id error: %w", err)
+	if err := r.collection.FindOne(ctx, mongoIDFilter(scope, id)).Decode(&item); err != nil {
+		return nil, fmt.Errorf("mongodb get by id error: %w", translateMongoError(err))
 	}
 	return &item, nil
 }
 
+func (r *MongoDBStrategy) Update(ctx context.Context, scope domain.Scope, id string, patch domain.ItemPatch) (*domain.Item, error) {
+	set := bson.M{"updated_at": time.Now()}
+	for k, v := range buildUpdateColumns(patch) {
+		set[k] = v
+	}
+
+	opts := options.FindOneAndUpdate().SetReturnDocument(options.After)
+
+	var item domain.Item
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := r.checkModify(sc, scope, id); err != nil {
+			return err
+		}
+		if err := r.collection.FindOneAndUpdate(sc, mongoIDFilter(scope, id), bson.M{"$set": set}, opts).Decode(&item); err != nil {
+			return err
+		}
+		return r.insertOutbox(sc, domain.EventItemUpdated, &item)
+	})
+	if err != nil {
+		return nil, fmt.Errorf("mongodb update error: %w", translateMongoError(err))
+	}
+	return &item, nil
+}
+
+func (r *MongoDBStrategy) Upsert(ctx context.Context, scope domain.Scope, item *domain.Item) (*domain.Item, bool, error) {
+	now := time.Now()
+	var (
+		doc     domain.Item
+		created bool
+	)
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		var existing domain.Item
+		err := r.collection.FindOne(sc, bson.M{"_id": item.ID}).Decode(&existing)
+		switch {
+		case errors.Is(err, mongo.ErrNoDocuments):
+			created = true
+		case err != nil:
+			return err
+		default:
+			if err := checkUpsert(scope, &existing); err != nil {
+				return err
+			}
+		}
+
+		// upsert để hai request cùng tạo một id không lỗi duplicate key
+		update := bson.M{
+			"$set": bson.M{"name": item.Name, "content": item.Content, "updated_at": now},
+			"$setOnInsert": bson.M{
+				"owner_id":   item.OwnerID,
+				"tenant_id":  item.TenantID,
+				"created_at": now,
+			},
+		}
+		opts := options.FindOneAndUpdate().SetUpsert(true).SetReturnDocument(options.After)
+		if err := r.collection.FindOneAndUpdate(sc, bson.M{"_id": item.ID}, update, opts).Decode(&doc); err != nil {
+			return err
+		}
+		eventType := domain.EventItemUpdated
+		if created {
+			eventType = domain.EventItemCreated
+		}
+		return r.insertOutbox(sc, eventType, &doc)
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("mongodb upsert error: %w", translateMongoError(err))
+	}
+	return &doc, created, nil
+}
+
+func (r *MongoDBStrategy) Delete(ctx context.Context, scope domain.Scope, id string) error {
+	err := r.withTransaction(ctx, func(sc mongo.SessionContext) error {
+		if err := r.checkModify(sc, scope, id); err != nil {
+			return err
+		}
+		var item domain.Item
+		if err := r.collection.FindOneAndDelete(sc, mongoIDFilter(scope, id)).Decode(&item); err != nil {
+			return err
+		}
+		return r.insertOutbox(sc, domain.EventItemDeleted, deletedPayload(&item))
+	})
+	if err != nil {
+		return fmt.Errorf("mongodb delete error: %w", translateMongoError(err))
+	}
+	return nil
+}
+
+// checkModify đọc item trong transaction để phân biệt item không thấy được
+// (ErrNotFound) với item của người khác (ErrForbidden).
+func (r *MongoDBStrategy) checkModify(sc mongo.SessionContext, scope domain.Scope, id string) error {
+	var item domain.Item
+	if err := r.collection.FindOne(sc, mongoIDFilter(scope, id)).Decode(&item); err != nil {
+		return err
+	}
+	return checkModify(scope, &item)
+}
+
 func (r *MongoDBStrategy) Ping(ctx context.Context) error {
 	return r.collection.Database().Client().Ping(ctx, nil)
 }
+
+func (r *MongoDBStrategy) Close(ctx context.Context) error {
+	if err := r.collection.Database().Client().Disconnect(ctx); err != nil {
+		return fmt.Errorf("mongodb close error: %w", translateMongoError(err))
+	}
+	return nil
+}
+
+func mongoIDFilter(scope domain.Scope, id string) bson.D {
+	return append(bson.D{{Key: "_id", Value: id}}, buildMongoScope(scope)...)
+}
+
+func mongoField(field string) string {
+	if field == "id" {
+		return "_id"
+	}
+	return field
+}
+
+func buildMongoKeysetFilter(params domain.ListParams, cursor *domain.Cursor, sortVal int) (bson.D, error) {
+	value, err := cursorArg(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	op := "$lt"
+	if sortVal == 1 {
+		op = "$gt"
+	}
+
+	field := mongoField(params.SortBy)
+	if field == "_id" {
+		return bson.D{{Key: "_id", Value: bson.M{op: cursor.ID}}}, nil
+	}
+	return bson.D{{Key: "$or", Value: bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: cursor.ID}},
+	}}}, nil
+}
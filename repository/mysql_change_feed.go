@@ -0,0 +1,145 @@
+package repository
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"gorm.io/gorm"
+)
+
+// tombstoneRetention: tombstone được giữ lại để poller ở replica khác kịp đọc.
+const tombstoneRetention = 24 * time.Hour
+
+// itemTombstone được trigger items_after_delete (migration 0004) ghi khi xoá item.
+type itemTombstone struct {
+	Seq       int64 `gorm:"primaryKey"`
+	ID        string
+	Name      string
+	OwnerID   string
+	TenantID  string
+	DeletedAt time.Time
+}
+
+func (itemTombstone) TableName() string { return "item_tombstones" }
+
+// mysqlPoller đọc thay đổi bằng cách polling: insert/update theo keyset
+// (updated_at, id), delete theo seq của item_tombstones.
+//
+// Đây là bản "binlog-lite": transaction commit trễ với updated_at nhỏ hơn
+// watermark sẽ bị bỏ qua, và nhiều lần update giữa hai lần poll chỉ ra một event.
+type mysqlPoller struct {
+	db        *gorm.DB
+	updatedAt time.Time
+	lastID    string
+	lastSeq   int64
+}
+
+func mysqlWatch(ctx context.Context, r *GormStrategy, out chan<- domain.ChangeEvent) {
+	p := &mysqlPoller{db: r.db}
+	for {
+		err := p.start(ctx)
+		if err == nil {
+			break
+		}
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[ChangeFeed] mysql start error: %v, retrying in %s", err, changeFeedRetry)
+		if !sleepCtx(ctx, changeFeedRetry) {
+			return
+		}
+	}
+
+	purge := time.NewTicker(time.Hour)
+	defer purge.Stop()
+	for {
+		if err := p.poll(ctx, out); err != nil && ctx.Err() == nil {
+			log.Printf("[ChangeFeed] mysql poll error: %v", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-purge.C:
+			before := r.db.NowFunc().Add(-tombstoneRetention)
+			if err := p.db.WithContext(ctx).Where("deleted_at < ?", before).Delete(&itemTombstone{}).Error; err != nil {
+				log.Printf("[ChangeFeed] mysql purge tombstones error: %v", err)
+			}
+		case <-time.After(changeFeedPollInterval):
+		}
+	}
+}
+
+// start đặt watermark tại thời điểm hiện tại, chỉ phát thay đổi từ đây về sau.
+func (p *mysqlPoller) start(ctx context.Context) error {
+	db := p.db.WithContext(ctx)
+
+	var last sqlItem
+	err := db.Where("updated_at IS NOT NULL").Order("updated_at DESC, id DESC").Limit(1).Find(&last).Error
+	if err != nil {
+		return err
+	}
+	p.updatedAt, p.lastID = last.UpdatedAt, last.ID
+
+	return db.Model(&itemTombstone{}).Select("COALESCE(MAX(seq), 0)").Scan(&p.lastSeq).Error
+}
+
+func (p *mysqlPoller) poll(ctx context.Context, out chan<- domain.ChangeEvent) error {
+	db := p.db.WithContext(ctx)
+
+	for {
+		var rows []sqlItem
+		err := db.Where("updated_at > ? OR (updated_at = ? AND id > ?)", p.updatedAt, p.updatedAt, p.lastID).
+			Order("updated_at, id").
+			Limit(changeFeedBatchSize).
+			Find(&rows).Error
+		if err != nil {
+			return err
+		}
+		for i := range rows {
+			eventType := domain.EventItemUpdated
+			if rows[i].CreatedAt.Equal(rows[i].UpdatedAt) {
+				eventType = domain.EventItemCreated
+			}
+			ev, err := newChangeEvent(eventType, toDomainItem(&rows[i]))
+			if err != nil {
+				return err
+			}
+			if !emit(ctx, out, ev) {
+				return ctx.Err()
+			}
+			p.updatedAt, p.lastID = rows[i].UpdatedAt, rows[i].ID
+		}
+		if len(rows) < changeFeedBatchSize {
+			break
+		}
+	}
+
+	for {
+		var tombstones []itemTombstone
+		err := db.Where("seq > ?", p.lastSeq).Order("seq").Limit(changeFeedBatchSize).Find(&tombstones).Error
+		if err != nil {
+			return err
+		}
+		for _, t := range tombstones {
+			ev, err := newChangeEvent(domain.EventItemDeleted, deletedPayload(&domain.Item{
+				ID:       t.ID,
+				Name:     t.Name,
+				OwnerID:  t.OwnerID,
+				TenantID: t.TenantID,
+			}))
+			if err != nil {
+				return err
+			}
+			if !emit(ctx, out, ev) {
+				return ctx.Err()
+			}
+			p.lastSeq = t.Seq
+		}
+		if len(tombstones) < changeFeedBatchSize {
+			return nil
+		}
+	}
+}
@@ -0,0 +1,29 @@
+package repository
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// deletedPayload là payload của ITEM_DELETED: item đã xoá nên chỉ còn id,
+// name và owner/tenant để hub biết client nào được nhận event.
+func deletedPayload(item *domain.Item) map[string]string {
+	payload := map[string]string{"id": item.ID, "name": item.Name}
+	if item.OwnerID != "" {
+		payload["owner_id"] = item.OwnerID
+	}
+	if item.TenantID != "" {
+		payload["tenant_id"] = item.TenantID
+	}
+	return payload
+}
+
+func marshalOutboxPayload(v interface{}) (string, error) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return "", fmt.Errorf("outbox payload error: %w", err)
+	}
+	return string(data), nil
+}
@@ -0,0 +1,118 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"github.com/jackc/pgx/v5/stdlib"
+)
+
+// Channel phải khớp với trigger notify_items_change (migration 0004).
+const postgresChangeChannel = "items_changes"
+
+// postgresChange là payload trigger gửi qua pg_notify. Chỉ có id và name vì
+// NOTIFY giới hạn 8000 byte; insert/update được đọc lại toàn bộ item.
+type postgresChange struct {
+	Op       string `json:"op"`
+	ID       string `json:"id"`
+	Name     string `json:"name"`
+	OwnerID  string `json:"owner_id"`
+	TenantID string `json:"tenant_id"`
+}
+
+func postgresWatch(ctx context.Context, r *GormStrategy, out chan<- domain.ChangeEvent) {
+	for {
+		err := postgresListen(ctx, r, out)
+		if ctx.Err() != nil {
+			return
+		}
+		log.Printf("[ChangeFeed] postgres listen error: %v, retrying in %s", err, changeFeedRetry)
+		if !sleepCtx(ctx, changeFeedRetry) {
+			return
+		}
+	}
+}
+
+// postgresListen giữ riêng một connection trong pool để LISTEN, trả về khi
+// connection lỗi hoặc ctx bị huỷ.
+func postgresListen(ctx context.Context, r *GormStrategy, out chan<- domain.ChangeEvent) error {
+	sqlDB, err := r.db.DB()
+	if err != nil {
+		return err
+	}
+	conn, err := sqlDB.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	return conn.Raw(func(driverConn any) error {
+		stdConn, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("unexpected driver connection %T", driverConn)
+		}
+		pgConn := stdConn.Conn()
+		if _, err := pgConn.Exec(ctx, "LISTEN "+postgresChangeChannel); err != nil {
+			return err
+		}
+		// connection quay lại pool sau khi thoát nên phải bỏ LISTEN; nếu
+		// connection đã hỏng thì pool tự loại bỏ nó.
+		defer pgConn.Exec(context.Background(), "UNLISTEN "+postgresChangeChannel)
+
+		for {
+			n, err := pgConn.WaitForNotification(ctx)
+			if err != nil {
+				return err
+			}
+			ev, ok, err := r.postgresChangeEvent(ctx, n.Payload)
+			if err != nil {
+				log.Printf("[ChangeFeed] postgres notification %q: %v", n.Payload, err)
+				continue
+			}
+			if ok && !emit(ctx, out, ev) {
+				return ctx.Err()
+			}
+		}
+	})
+}
+
+// postgresChangeEvent trả về ok=false khi item đã bị xoá trước khi kịp đọc
+// lại; notification DELETE theo sau sẽ phát event.
+func (r *GormStrategy) postgresChangeEvent(ctx context.Context, payload string) (domain.ChangeEvent, bool, error) {
+	var change postgresChange
+	if err := json.Unmarshal([]byte(payload), &change); err != nil {
+		return domain.ChangeEvent{}, false, err
+	}
+
+	var eventType string
+	switch change.Op {
+	case "INSERT":
+		eventType = domain.EventItemCreated
+	case "UPDATE":
+		eventType = domain.EventItemUpdated
+	case "DELETE":
+		ev, err := newChangeEvent(domain.EventItemDeleted, deletedPayload(&domain.Item{
+			ID:       change.ID,
+			Name:     change.Name,
+			OwnerID:  change.OwnerID,
+			TenantID: change.TenantID,
+		}))
+		return ev, err == nil, err
+	default:
+		return domain.ChangeEvent{}, false, fmt.Errorf("unknown op %s", change.Op)
+	}
+
+	item, err := r.GetByID(ctx, domain.Scope{}, change.ID)
+	if errors.Is(err, domain.ErrNotFound) {
+		return domain.ChangeEvent{}, false, nil
+	}
+	if err != nil {
+		return domain.ChangeEvent{}, false, err
+	}
+	ev, err := newChangeEvent(eventType, item)
+	return ev, err == nil, err
+}
@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// sqlItem là row model chung cho mọi strategy chạy trên gorm.
+type sqlItem struct {
+	ID        string    `gorm:"primaryKey;type:varchar(36)"`
+	Name      string    `gorm:"type:varchar(255);not null"`
+	Content   string    `gorm:"type:text"`
+	OwnerID   string    `gorm:"type:varchar(255);not null;default:''"`
+	TenantID  string    `gorm:"type:varchar(255);not null;default:''"`
+	CreatedAt time.Time `gorm:"autoCreateTime;index"`
+	UpdatedAt time.Time `gorm:"autoUpdateTime"`
+}
+
+func (sqlItem) TableName() string { return "items" }
+
+func toDomainItem(row *sqlItem) *domain.Item {
+	return &domain.Item{
+		ID:        row.ID,
+		Name:      row.Name,
+		Content:   row.Content,
+		OwnerID:   row.OwnerID,
+		TenantID:  row.TenantID,
+		CreatedAt: row.CreatedAt,
+		UpdatedAt: row.UpdatedAt,
+	}
+}
@@ -0,0 +1,43 @@
+package repository
+
+import (
+	"strings"
+	"time"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+var sqliteDialect = GormDialect{
+	Name:   "sqlite",
+	Search: sqliteSearch,
+	Upsert: onConflictUpsert,
+	// SQLite lưu thời gian dạng text, giữ mọi giá trị ở UTC để so sánh chuỗi đúng thứ tự
+	NowFunc: func() time.Time { return time.Now().UTC() },
+	Setup: func(db *gorm.DB) error {
+		// SQLite chỉ cho một writer tại một thời điểm; một connection duy nhất
+		// tránh lỗi "database is locked" và giữ được DSN ":memory:".
+		sqlDB, err := db.DB()
+		if err != nil {
+			return err
+		}
+		sqlDB.SetMaxOpenConns(1)
+		return nil
+	},
+}
+
+func NewSQLiteStrategy(dsn string) (*GormStrategy, error) {
+	// DSN là đường dẫn file, ví dụ "items.db" hoặc "file:items.db?_busy_timeout=5000"
+	return NewGormStrategy(sqlite.Open(dsn), sqliteDialect)
+}
+
+// sqliteSearch không dùng FTS5 (cần build tag riêng cho go-sqlite3) mà
+// yêu cầu mọi từ trong q xuất hiện trong name hoặc content.
+func sqliteSearch(db *gorm.DB, q string) *gorm.DB {
+	escaper := strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`)
+	for _, term := range strings.Fields(q) {
+		pattern := "%" + escaper.Replace(term) + "%"
+		db = db.Where(`(name LIKE ? ESCAPE '\' OR content LIKE ? ESCAPE '\')`, pattern, pattern)
+	}
+	return db
+}
@@ -0,0 +1,136 @@
+package repository
+
+import (
+	"context"
+	"os"
+	"strings"
+	"testing"
+
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// Strategy dùng DB thật chỉ chạy khi có biến môi trường tương ứng, ví dụ:
+//
+//	REPOSITORY_TEST_POSTGRES_DSN="host=localhost user=postgres password=postgres dbname=items_test port=5432 sslmode=disable"
+//	REPOSITORY_TEST_MYSQL_DSN="root:root@tcp(localhost:3306)/items_test?charset=utf8mb4&parseTime=True&loc=Local"
+//	REPOSITORY_TEST_MONGO_URI="mongodb://localhost:27017/?replicaSet=rs0"
+//
+// Các test này xoá sạch bảng/collection items và outbox trước mỗi subtest.
+
+func TestMemoryStrategy(t *testing.T) {
+	runConformance(t, func(t *testing.T) ItemRepository {
+		return NewMemoryStrategy()
+	})
+}
+
+func TestSQLiteStrategy(t *testing.T) {
+	runConformance(t, func(t *testing.T) ItemRepository {
+		repo, err := NewSQLiteStrategy(":memory:")
+		if err != nil {
+			t.Fatalf("sqlite: %v", err)
+		}
+		return migrated(t, repo)
+	})
+}
+
+func TestPostgresStrategy(t *testing.T) {
+	dsn := requireEnv(t, "REPOSITORY_TEST_POSTGRES_DSN")
+	runConformance(t, func(t *testing.T) ItemRepository {
+		repo, err := NewPostgresStrategy(dsn)
+		if err != nil {
+			t.Fatalf("postgres: %v", err)
+		}
+		return truncateGorm(t, migrated(t, repo))
+	})
+}
+
+func TestMySQLStrategy(t *testing.T) {
+	dsn := requireEnv(t, "REPOSITORY_TEST_MYSQL_DSN")
+	runConformance(t, func(t *testing.T) ItemRepository {
+		repo, err := NewMySQLStrategy(dsn)
+		if err != nil {
+			t.Fatalf("mysql: %v", err)
+		}
+		return truncateGorm(t, migrated(t, repo))
+	})
+}
+
+func TestMongoDBStrategy(t *testing.T) {
+	uri := requireEnv(t, "REPOSITORY_TEST_MONGO_URI")
+	runConformance(t, func(t *testing.T) ItemRepository {
+		repo, err := NewMongoDBStrategy(uri, "items_test", "items")
+		if err != nil {
+			t.Fatalf("mongodb: %v", err)
+		}
+		migrated(t, repo)
+		for _, coll := range []*mongo.Collection{repo.collection, repo.outbox} {
+			if _, err := coll.DeleteMany(context.Background(), bson.M{}); err != nil {
+				t.Fatalf("mongodb cleanup: %v", err)
+			}
+		}
+		return repo
+	})
+}
+
+func requireEnv(t *testing.T, key string) string {
+	t.Helper()
+	v := os.Getenv(key)
+	if v == "" {
+		t.Skipf("%s not set", key)
+	}
+	return v
+}
+
+func migrated[R migratable](t *testing.T, repo R) R {
+	t.Helper()
+	if _, err := repo.Migrator().Up(context.Background()); err != nil {
+		t.Fatalf("migrate up: %v", err)
+	}
+	return repo
+}
+
+func truncateGorm(t *testing.T, repo *GormStrategy) *GormStrategy {
+	t.Helper()
+	for _, table := range []string{"items", "outbox"} {
+		if err := repo.db.Exec("DELETE FROM " + table).Error; err != nil {
+			t.Fatalf("%s cleanup: %v", repo.dialect.Name, err)
+		}
+	}
+	return repo
+}
+
+// TestGormDialectUpsertSQL kiểm tra câu INSERT của Upsert cho các dialect
+// không có DB thật trong CI, dùng DryRun nên không mở kết nối.
+func TestGormDialectUpsertSQL(t *testing.T) {
+	cases := []struct {
+		name      string
+		dialector gorm.Dialector
+		dialect   GormDialect
+		want      string
+	}{
+		{"postgres", postgres.New(postgres.Config{DSN: "host=localhost"}), postgresDialect, `ON CONFLICT ("id") DO UPDATE SET "name"="excluded"."name","content"="excluded"."content","updated_at"="excluded"."updated_at"`},
+		{"mysql", mysql.New(mysql.Config{DSN: "root@tcp(localhost:3306)/items", SkipInitializeWithVersion: true}), mysqlDialect, "ON DUPLICATE KEY UPDATE `name`=VALUES(`name`),`content`=VALUES(`content`),`updated_at`=VALUES(`updated_at`)"},
+		{"sqlite", sqlite.Open(":memory:"), sqliteDialect, `ON CONFLICT (` + "`id`" + `) DO UPDATE SET ` + "`name`=`excluded`.`name`"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			db, err := gorm.Open(tc.dialector, &gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+			if err != nil {
+				t.Fatalf("open: %v", err)
+			}
+			res := db.Clauses(tc.dialect.Upsert([]string{"name", "content", "updated_at"})).
+				Create(&sqlItem{ID: "id-1", Name: "n"})
+			if res.Error != nil {
+				t.Fatalf("dry run: %v", res.Error)
+			}
+			if sql := res.Statement.SQL.String(); !strings.Contains(sql, tc.want) {
+				t.Fatalf("sql = %s\nwant it to contain %s", sql, tc.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,277 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// TenantRouter đưa mỗi tenant có cấu hình riêng (DBConfig.TenantDSNs) vào
+// database/schema riêng của nó; tenant còn lại và scope rỗng dùng repo chung.
+// Scope vẫn được áp dụng trong từng repo nên dữ liệu bị lọc hai lớp.
+type TenantRouter struct {
+	shared  ItemRepository
+	tenants map[string]ItemRepository
+}
+
+func NewTenantRouter(shared ItemRepository, tenants map[string]ItemRepository) *TenantRouter {
+	return &TenantRouter{shared: shared, tenants: tenants}
+}
+
+func (r *TenantRouter) repoFor(tenantID string) ItemRepository {
+	if repo, ok := r.tenants[tenantID]; ok {
+		return repo
+	}
+	return r.shared
+}
+
+// each gọi fn cho repo chung (tenant "") rồi từng tenant theo thứ tự tên.
+func (r *TenantRouter) each(fn func(tenantID string, repo ItemRepository) error) error {
+	if err := fn("", r.shared); err != nil {
+		return err
+	}
+	names := make([]string, 0, len(r.tenants))
+	for name := range r.tenants {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if err := fn(name, r.tenants[name]); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TenantRouter) Create(ctx context.Context, item *domain.Item) (*domain.Item, error) {
+	return r.repoFor(item.TenantID).Create(ctx, item)
+}
+
+func (r *TenantRouter) List(ctx context.Context, scope domain.Scope, params domain.ListParams) (*domain.ListResult, error) {
+	return r.repoFor(scope.TenantID).List(ctx, scope, params)
+}
+
+func (r *TenantRouter) GetByID(ctx context.Context, scope domain.Scope, id string) (*domain.Item, error) {
+	return r.repoFor(scope.TenantID).GetByID(ctx, scope, id)
+}
+
+func (r *TenantRouter) Update(ctx context.Context, scope domain.Scope, id string, patch domain.ItemPatch) (*domain.Item, error) {
+	return r.repoFor(scope.TenantID).Update(ctx, scope, id, patch)
+}
+
+func (r *TenantRouter) Upsert(ctx context.Context, scope domain.Scope, item *domain.Item) (*domain.Item, bool, error) {
+	return r.repoFor(scope.TenantID).Upsert(ctx, scope, item)
+}
+
+func (r *TenantRouter) Delete(ctx context.Context, scope domain.Scope, id string) error {
+	return r.repoFor(scope.TenantID).Delete(ctx, scope, id)
+}
+
+func (r *TenantRouter) Ping(ctx context.Context) error {
+	return r.each(func(tenantID string, repo ItemRepository) error {
+		if err := repo.Ping(ctx); err != nil {
+			return fmt.Errorf("tenant %q: %w", tenantID, err)
+		}
+		return nil
+	})
+}
+
+// Close đóng mọi database, kể cả khi có database đóng lỗi.
+func (r *TenantRouter) Close(ctx context.Context) error {
+	var errs []error
+	r.each(func(tenantID string, repo ItemRepository) error {
+		if err := repo.Close(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", tenantID, err))
+		}
+		return nil
+	})
+	return errors.Join(errs...)
+}
+
+// FetchPending gom outbox của mọi database. ID của event trong database
+// riêng được thêm prefix "<tenant>/" để MarkDelivered biết trả về đâu.
+func (r *TenantRouter) FetchPending(ctx context.Context, limit int) ([]*domain.OutboxEvent, error) {
+	return r.pending(limit, func(repo ItemRepository, n int) ([]*domain.OutboxEvent, error) {
+		return repo.FetchPending(ctx, n)
+	})
+}
+
+func (r *TenantRouter) ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*domain.OutboxEvent, error) {
+	return r.pending(limit, func(repo ItemRepository, n int) ([]*domain.OutboxEvent, error) {
+		return repo.ClaimPending(ctx, owner, n, lease)
+	})
+}
+
+func (r *TenantRouter) pending(limit int, fetch func(repo ItemRepository, n int) ([]*domain.OutboxEvent, error)) ([]*domain.OutboxEvent, error) {
+	var out []*domain.OutboxEvent
+	err := r.each(func(tenantID string, repo ItemRepository) error {
+		if len(out) >= limit {
+			return nil
+		}
+		events, err := fetch(repo, limit-len(out))
+		if err != nil {
+			return err
+		}
+		for _, ev := range events {
+			if tenantID != "" {
+				ev.ID = tenantID + "/" + ev.ID
+			}
+			out = append(out, ev)
+		}
+		return nil
+	})
+	return out, err
+}
+
+func (r *TenantRouter) MarkDelivered(ctx context.Context, ids []string) error {
+	byTenant := make(map[string][]string)
+	for _, id := range ids {
+		tenantID, rawID, ok := strings.Cut(id, "/")
+		if !ok {
+			tenantID, rawID = "", id
+		}
+		byTenant[tenantID] = append(byTenant[tenantID], rawID)
+	}
+	for tenantID, ids := range byTenant {
+		repo := r.shared
+		if tenantID != "" {
+			var ok bool
+			if repo, ok = r.tenants[tenantID]; !ok {
+				return fmt.Errorf("outbox: unknown tenant %q", tenantID)
+			}
+		}
+		if err := repo.MarkDelivered(ctx, ids); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *TenantRouter) PurgeDelivered(ctx context.Context, before time.Time) error {
+	return r.each(func(_ string, repo ItemRepository) error {
+		return repo.PurgeDelivered(ctx, before)
+	})
+}
+
+// Watch gộp change feed của mọi database; chỉ hỗ trợ khi tất cả đều có feed.
+func (r *TenantRouter) Watch(ctx context.Context) (<-chan domain.ChangeEvent, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	var feeds []<-chan domain.ChangeEvent
+	err := r.each(func(tenantID string, repo ItemRepository) error {
+		feed, ok := repo.(ChangeFeed)
+		if !ok {
+			return fmt.Errorf("tenant %q change feed: %w", tenantID, errors.ErrUnsupported)
+		}
+		ch, err := feed.Watch(ctx)
+		if err != nil {
+			return fmt.Errorf("tenant %q: %w", tenantID, err)
+		}
+		feeds = append(feeds, ch)
+		return nil
+	})
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+
+	out := make(chan domain.ChangeEvent, changeFeedBuffer)
+	var wg sync.WaitGroup
+	for _, ch := range feeds {
+		wg.Add(1)
+		go func(ch <-chan domain.ChangeEvent) {
+			defer wg.Done()
+			for ev := range ch {
+				if !emit(ctx, out, ev) {
+					return
+				}
+			}
+		}(ch)
+	}
+	go func() {
+		wg.Wait()
+		cancel()
+		close(out)
+	}()
+	return out, nil
+}
+
+// Migrator chạy migration trên mọi database có schema cần quản lý.
+func (r *TenantRouter) Migrator() Migrator {
+	var m tenantMigrator
+	r.each(func(tenantID string, repo ItemRepository) error {
+		if mr, ok := repo.(migratable); ok {
+			m.names = append(m.names, tenantID)
+			m.migrators = append(m.migrators, mr.Migrator())
+		}
+		return nil
+	})
+	return &m
+}
+
+type tenantMigrator struct {
+	names     []string
+	migrators []Migrator
+}
+
+// Status coi một migration là đã áp dụng chỉ khi mọi database đều đã chạy nó.
+func (m *tenantMigrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	var merged []MigrationStatus
+	for i, mg := range m.migrators {
+		status, err := mg.Status(ctx)
+		if err != nil {
+			return nil, fmt.Errorf("tenant %q: %w", m.names[i], err)
+		}
+		if merged == nil {
+			merged = status
+			continue
+		}
+		for j := range merged {
+			if j >= len(status) || !status[j].Applied {
+				merged[j].Applied = false
+				merged[j].AppliedAt = time.Time{}
+			}
+		}
+	}
+	return merged, nil
+}
+
+// Close đóng database của mọi tenant, kể cả khi có database đóng lỗi.
+func (m *tenantMigrator) Close() error {
+	var errs []error
+	for i, mg := range m.migrators {
+		if err := mg.Close(); err != nil {
+			errs = append(errs, fmt.Errorf("tenant %q: %w", m.names[i], err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (m *tenantMigrator) Up(ctx context.Context) (int, error) {
+	total := 0
+	for i, mg := range m.migrators {
+		n, err := mg.Up(ctx)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("tenant %q: %w", m.names[i], err)
+		}
+	}
+	return total, nil
+}
+
+func (m *tenantMigrator) Down(ctx context.Context, steps int) (int, error) {
+	total := 0
+	for i, mg := range m.migrators {
+		n, err := mg.Down(ctx, steps)
+		total += n
+		if err != nil {
+			return total, fmt.Errorf("tenant %q: %w", m.names[i], err)
+		}
+	}
+	return total, nil
+}
@@ -0,0 +1,69 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+func TestTenantRouter(t *testing.T) {
+	ctx := context.Background()
+	shared, acmeDB := NewMemoryStrategy(), NewMemoryStrategy()
+	router := NewTenantRouter(shared, map[string]ItemRepository{"acme": acmeDB})
+
+	acme := domain.Scope{OwnerID: "alice", TenantID: "acme"}
+	item, err := router.Create(ctx, &domain.Item{Name: "a", OwnerID: "alice", TenantID: "acme"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	other, err := router.Create(ctx, &domain.Item{Name: "b", OwnerID: "bob", TenantID: "globex"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	// item của acme chỉ nằm trong database của acme
+	if _, err := acmeDB.GetByID(ctx, domain.Scope{}, item.ID); err != nil {
+		t.Fatalf("acme item not in acme database: %v", err)
+	}
+	if _, err := shared.GetByID(ctx, domain.Scope{}, item.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("acme item leaked to shared database: %v", err)
+	}
+	if _, err := shared.GetByID(ctx, domain.Scope{}, other.ID); err != nil {
+		t.Fatalf("unrouted tenant not in shared database: %v", err)
+	}
+	if _, err := router.GetByID(ctx, acme, item.ID); err != nil {
+		t.Fatalf("get via router: %v", err)
+	}
+	if _, err := router.GetByID(ctx, domain.Scope{TenantID: "globex"}, item.ID); !errors.Is(err, domain.ErrNotFound) {
+		t.Fatalf("get from other tenant: got %v, want ErrNotFound", err)
+	}
+
+	// outbox gom từ mọi database, MarkDelivered trả về đúng chỗ
+	pending, err := router.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("fetch pending: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("pending = %d, want 2", len(pending))
+	}
+	var ids []string
+	prefixed := 0
+	for _, ev := range pending {
+		if strings.HasPrefix(ev.ID, "acme/") {
+			prefixed++
+		}
+		ids = append(ids, ev.ID)
+	}
+	if prefixed != 1 {
+		t.Fatalf("got %d acme-prefixed ids in %v, want 1", prefixed, ids)
+	}
+	if err := router.MarkDelivered(ctx, ids); err != nil {
+		t.Fatalf("mark delivered: %v", err)
+	}
+	if pending, _ := router.FetchPending(ctx, 10); len(pending) != 0 {
+		t.Fatalf("pending after mark = %d, want 0", len(pending))
+	}
+}
@@ -0,0 +1,47 @@
+package service
+
+import (
+	"context"
+	"log"
+
+	"github.com/JIeeiroSst/hub/repository"
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+// ChangeFeedRelay đẩy thay đổi đọc từ chính DB ra hub, nên cả dữ liệu do
+// service khác hay psql ghi vào cũng tới được client. Mỗi replica tự đọc
+// change feed nên chỉ broadcast local, không qua backplane.
+type ChangeFeedRelay struct {
+	feed repository.ChangeFeed
+	hub  *ws.Hub
+	done chan struct{}
+}
+
+func NewChangeFeedRelay(feed repository.ChangeFeed, hub *ws.Hub) *ChangeFeedRelay {
+	return &ChangeFeedRelay{feed: feed, hub: hub, done: make(chan struct{})}
+}
+
+// Start mở change feed rồi chạy nền tới khi ctx bị huỷ.
+func (r *ChangeFeedRelay) Start(ctx context.Context) error {
+	events, err := r.feed.Watch(ctx)
+	if err != nil {
+		return err
+	}
+
+	go func() {
+		defer close(r.done)
+		for ev := range events {
+			// change feed không đọc lại được event đã qua nên chỉ log khi hub quá tải
+			if err := r.hub.BroadcastLocal(ctx, ws.EventType(ev.Type), ev.Payload); err != nil {
+				log.Printf("[ChangeFeed] broadcast error: %v", err)
+			}
+		}
+		log.Printf("[ChangeFeed] stopped")
+	}()
+	return nil
+}
+
+// Done đóng khi relay đã dừng hẳn sau khi ctx của Start bị huỷ.
+func (r *ChangeFeedRelay) Done() <-chan struct{} {
+	return r.done
+}
@@ -5,30 +5,35 @@ import (
 	"fmt"
 	"time"
 
+	"github.com/JIeeiroSst/hub/auth"
 	"github.com/JIeeiroSst/hub/domain"
 	"github.com/JIeeiroSst/hub/repository"
-	ws "github.com/JIeeiroSst/hub/websocket"
+	"github.com/google/uuid"
 )
 
-
+// ItemService không broadcast trực tiếp: repository ghi event vào outbox cùng
+// transaction với thay đổi, relay chịu trách nhiệm đẩy ra hub. Mọi thao tác
+// bị giới hạn theo scope của principal trong ctx.
 type ItemService struct {
-	repo repository.ItemRepository
-	hub  *ws.Hub
+	repo  repository.ItemRepository
+	relay *OutboxRelay
 }
 
-func NewItemService(repo repository.ItemRepository, hub *ws.Hub) *ItemService {
-	return &ItemService{repo: repo, hub: hub}
+func NewItemService(repo repository.ItemRepository, relay *OutboxRelay) *ItemService {
+	return &ItemService{repo: repo, relay: relay}
 }
 
-
 func (s *ItemService) Create(ctx context.Context, name, content string) (*domain.Item, error) {
 	if name == "" {
-		return nil, fmt.Errorf("name is required")
+		return nil, fmt.Errorf("%w: name is required", domain.ErrValidation)
 	}
 
+	scope := auth.ScopeFrom(ctx)
 	item := &domain.Item{
-		Name:    name,
-		Content: content,
+		Name:     name,
+		Content:  content,
+		OwnerID:  scope.OwnerID,
+		TenantID: scope.TenantID,
 	}
 
 	created, err := s.repo.Create(ctx, item)
@@ -36,7 +41,7 @@ func (s *ItemService) Create(ctx context.Context, name, content string) (*domain
 		return nil, fmt.Errorf("create item failed: %w", err)
 	}
 
-	s.hub.Broadcast(ws.EventItemCreated, created)
+	s.relay.Notify()
 
 	return created, nil
 }
@@ -44,7 +49,7 @@ func (s *ItemService) Create(ctx context.Context, name, content string) (*domain
 func (s *ItemService) List(ctx context.Context, params domain.ListParams) (*domain.ListResult, error) {
 	params.SetDefaults()
 
-	result, err := s.repo.List(ctx, params)
+	result, err := s.repo.List(ctx, auth.ScopeFrom(ctx), params)
 	if err != nil {
 		return nil, fmt.Errorf("list items failed: %w", err)
 	}
@@ -52,13 +57,70 @@ func (s *ItemService) List(ctx context.Context, params domain.ListParams) (*doma
 }
 
 func (s *ItemService) GetByID(ctx context.Context, id string) (*domain.Item, error) {
-	item, err := s.repo.GetByID(ctx, id)
+	item, err := s.repo.GetByID(ctx, auth.ScopeFrom(ctx), id)
 	if err != nil {
 		return nil, fmt.Errorf("get item failed: %w", err)
 	}
 	return item, nil
 }
 
+func (s *ItemService) Update(ctx context.Context, id string, patch domain.ItemPatch) (*domain.Item, error) {
+	if patch.IsEmpty() {
+		return nil, fmt.Errorf("%w: nothing to update", domain.ErrValidation)
+	}
+	if patch.Name != nil && *patch.Name == "" {
+		return nil, fmt.Errorf("%w: name is required", domain.ErrValidation)
+	}
+
+	updated, err := s.repo.Update(ctx, auth.ScopeFrom(ctx), id, patch)
+	if err != nil {
+		return nil, fmt.Errorf("update item failed: %w", err)
+	}
+
+	s.relay.Notify()
+
+	return updated, nil
+}
+
+// Replace ghi đè name/content của item, chưa có thì tạo mới với id do client
+// chọn (PUT idempotent); created báo item vừa được tạo.
+func (s *ItemService) Replace(ctx context.Context, id, name, content string) (*domain.Item, bool, error) {
+	if name == "" {
+		return nil, false, fmt.Errorf("%w: name is required", domain.ErrValidation)
+	}
+	if _, err := uuid.Parse(id); err != nil {
+		return nil, false, fmt.Errorf("%w: id must be a UUID", domain.ErrValidation)
+	}
+
+	scope := auth.ScopeFrom(ctx)
+	item := &domain.Item{
+		ID:       id,
+		Name:     name,
+		Content:  content,
+		OwnerID:  scope.OwnerID,
+		TenantID: scope.TenantID,
+	}
+
+	result, created, err := s.repo.Upsert(ctx, scope, item)
+	if err != nil {
+		return nil, false, fmt.Errorf("replace item failed: %w", err)
+	}
+
+	s.relay.Notify()
+
+	return result, created, nil
+}
+
+func (s *ItemService) Delete(ctx context.Context, id string) error {
+	if err := s.repo.Delete(ctx, auth.ScopeFrom(ctx), id); err != nil {
+		return fmt.Errorf("delete item failed: %w", err)
+	}
+
+	s.relay.Notify()
+
+	return nil
+}
+
 func (s *ItemService) HealthCheck(ctx context.Context) error {
 	ctx, cancel := context.WithTimeout(ctx, 3*time.Second)
 	defer cancel()
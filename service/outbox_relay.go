@@ -0,0 +1,119 @@
+package service
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/JIeeiroSst/hub/repository"
+	ws "github.com/JIeeiroSst/hub/websocket"
+	"github.com/google/uuid"
+)
+
+const (
+	relayPollInterval  = 500 * time.Millisecond
+	relayBatchSize     = 100
+	relayRetention     = 24 * time.Hour
+	relayPurgeInterval = 10 * time.Minute
+	// relayClaimLease: relay chết giữa chừng thì event nó đang giữ được relay
+	// khác nhận lại sau khoảng này
+	relayClaimLease = 30 * time.Second
+)
+
+// OutboxRelay đọc các event chưa giao trong outbox, broadcast qua hub rồi
+// đánh dấu đã giao. Event chỉ bị đánh dấu sau khi broadcast nên khi process
+// chết giữa chừng event sẽ được gửi lại (at-least-once); client dedupe theo id.
+//
+// Mỗi replica chạy một relay trên cùng outbox: event được claim cho một relay
+// (xem OutboxStore.ClaimPending) nên chỉ relay đó broadcast, không phải mỗi
+// replica một lần.
+type OutboxRelay struct {
+	store repository.OutboxStore
+	hub   *ws.Hub
+	owner string
+	wake  chan struct{}
+}
+
+// NewOutboxRelay với hub nil chỉ đánh dấu event đã giao mà không broadcast,
+// dùng khi ChangeFeedRelay đã phát event từ chính DB.
+func NewOutboxRelay(store repository.OutboxStore, hub *ws.Hub) *OutboxRelay {
+	return &OutboxRelay{
+		store: store,
+		hub:   hub,
+		owner: uuid.NewString(),
+		wake:  make(chan struct{}, 1),
+	}
+}
+
+// Notify báo relay có event mới để không phải đợi tới lần poll kế tiếp.
+// Không block; poll định kỳ vẫn là đường dự phòng.
+func (r *OutboxRelay) Notify() {
+	select {
+	case r.wake <- struct{}{}:
+	default:
+	}
+}
+
+// Run chạy tới khi ctx bị huỷ.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(relayPollInterval)
+	defer ticker.Stop()
+	purge := time.NewTicker(relayPurgeInterval)
+	defer purge.Stop()
+
+	for {
+		r.drain(ctx)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-r.wake:
+		case <-ticker.C:
+		case <-purge.C:
+			if err := r.store.PurgeDelivered(ctx, time.Now().Add(-relayRetention)); err != nil {
+				log.Printf("[Outbox] purge error: %v", err)
+			}
+		}
+	}
+}
+
+// drain gửi hết các event đang chờ theo từng batch.
+func (r *OutboxRelay) drain(ctx context.Context) {
+	for ctx.Err() == nil {
+		events, err := r.store.ClaimPending(ctx, r.owner, relayBatchSize, relayClaimLease)
+		if err != nil {
+			log.Printf("[Outbox] claim error: %v", err)
+			return
+		}
+		if len(events) == 0 {
+			return
+		}
+
+		// hub quá tải thì dừng ở event đầu tiên không broadcast được; phần còn
+		// lại vẫn nằm trong outbox (relay này vẫn giữ claim) và được gửi lại ở
+		// lần poll sau, đúng thứ tự
+		ids := make([]string, 0, len(events))
+		var broadcastErr error
+		for _, ev := range events {
+			if r.hub != nil {
+				if broadcastErr = r.hub.Broadcast(ctx, ws.EventType(ev.Type), ev.Payload); broadcastErr != nil {
+					break
+				}
+			}
+			ids = append(ids, ev.ID)
+		}
+		if len(ids) > 0 {
+			if err := r.store.MarkDelivered(ctx, ids); err != nil {
+				log.Printf("[Outbox] mark delivered error: %v", err)
+				return
+			}
+		}
+		if broadcastErr != nil {
+			log.Printf("[Outbox] broadcast error, retrying later: %v", broadcastErr)
+			return
+		}
+		if len(events) < relayBatchSize {
+			return
+		}
+	}
+}
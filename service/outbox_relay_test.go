@@ -0,0 +1,170 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+	"github.com/JIeeiroSst/hub/repository"
+	ws "github.com/JIeeiroSst/hub/websocket"
+)
+
+func TestOutboxRelayDeliversPendingEvents(t *testing.T) {
+	repo := repository.NewMemoryStrategy()
+	hub := ws.NewHub()
+	go hub.Run()
+
+	// ghi trước khi relay chạy: mô phỏng event còn sót lại sau khi process restart
+	ctx := context.Background()
+	item, err := repo.Create(ctx, &domain.Item{Name: "pending"})
+	if err != nil {
+		t.Fatalf("create: %v", err)
+	}
+
+	relay := NewOutboxRelay(repo, hub)
+	runCtx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go relay.Run(runCtx)
+
+	svc := NewItemService(repo, relay)
+	if err := svc.Delete(ctx, item.ID); err != nil {
+		t.Fatalf("delete: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		pending, err := repo.FetchPending(ctx, 10)
+		if err != nil {
+			t.Fatalf("fetch pending: %v", err)
+		}
+		if len(pending) == 0 && hub.LastSeq("") == 2 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("relay did not deliver: %d pending, hub seq %d", len(pending), hub.LastSeq(""))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestOutboxRelayKeepsEventsWhenHubOverloaded(t *testing.T) {
+	repo := repository.NewMemoryStrategy()
+	// hub chưa chạy Run: hàng đợi broadcast đầy thì event bị từ chối ngay
+	hub := ws.NewHub(ws.WithOverflowPolicy(ws.OverflowDrop, 0))
+	ctx := context.Background()
+	for {
+		if err := hub.Broadcast(ctx, ws.EventItemCreated, "filler"); err != nil {
+			break
+		}
+	}
+
+	for _, name := range []string{"a", "b"} {
+		if _, err := repo.Create(ctx, &domain.Item{Name: name}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	relay := NewOutboxRelay(repo, hub)
+	relay.drain(ctx)
+	pending, err := repo.FetchPending(ctx, 10)
+	if err != nil {
+		t.Fatalf("fetch pending: %v", err)
+	}
+	if len(pending) != 2 {
+		t.Fatalf("%d event(s) pending, want 2 kept in the outbox", len(pending))
+	}
+
+	go hub.Run()
+	defer hub.Shutdown(ctx)
+	deadline := time.Now().Add(2 * time.Second)
+	for len(pending) > 0 {
+		if time.Now().After(deadline) {
+			t.Fatalf("relay did not deliver after hub recovered: %d pending", len(pending))
+		}
+		time.Sleep(10 * time.Millisecond)
+		relay.drain(ctx)
+		if pending, err = repo.FetchPending(ctx, 10); err != nil {
+			t.Fatalf("fetch pending: %v", err)
+		}
+	}
+}
+
+// lockstepStore cho lần đọc đầu tiên của hai relay cùng trả về trước khi
+// relay nào kịp MarkDelivered, để hai relay chắc chắn chạy chồng lên nhau.
+type lockstepStore struct {
+	repository.OutboxStore
+	calls   atomic.Int32
+	arrived sync.WaitGroup
+}
+
+func (s *lockstepStore) ClaimPending(ctx context.Context, owner string, limit int, lease time.Duration) ([]*domain.OutboxEvent, error) {
+	events, err := s.OutboxStore.ClaimPending(ctx, owner, limit, lease)
+	if s.calls.Add(1) <= 2 {
+		s.arrived.Done()
+		s.arrived.Wait()
+	}
+	return events, err
+}
+
+// Hai replica chạy relay trên cùng outbox: mỗi event chỉ được broadcast một lần.
+func TestOutboxRelaysShareOutboxExactlyOnce(t *testing.T) {
+	repo := repository.NewMemoryStrategy()
+	hub := ws.NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+	client := ws.NewStreamClient(hub, nil, domain.Scope{}, "watcher")
+
+	ctx := context.Background()
+	const n = 50
+	for i := 0; i < n; i++ {
+		if _, err := repo.Create(ctx, &domain.Item{Name: fmt.Sprintf("item-%d", i)}); err != nil {
+			t.Fatalf("create: %v", err)
+		}
+	}
+
+	store := &lockstepStore{OutboxStore: repo}
+	store.arrived.Add(2)
+	var wg sync.WaitGroup
+	for i := 0; i < 2; i++ {
+		relay := NewOutboxRelay(store, hub)
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			relay.drain(ctx)
+		}()
+	}
+	wg.Wait()
+
+	if pending, err := repo.FetchPending(ctx, n); err != nil || len(pending) != 0 {
+		t.Fatalf("%d event(s) still pending, err %v", len(pending), err)
+	}
+	seen := make(map[string]bool)
+	timeout := time.After(2 * time.Second)
+	for len(seen) < n {
+		select {
+		case data := <-client.Events():
+			var ev struct {
+				Payload domain.Item `json:"payload"`
+			}
+			if err := json.Unmarshal(data, &ev); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if seen[ev.Payload.ID] {
+				t.Fatalf("item %s delivered twice", ev.Payload.ID)
+			}
+			seen[ev.Payload.ID] = true
+		case <-timeout:
+			t.Fatalf("got %d of %d events", len(seen), n)
+		}
+	}
+	select {
+	case data := <-client.Events():
+		t.Fatalf("unexpected extra event %s", data)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
@@ -0,0 +1,157 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"log"
+	"sync"
+)
+
+// Backplane nối các Hub ở nhiều replica: Broadcast publish event vào
+// backplane và mọi Hub (kể cả Hub vừa publish) nhận lại để fan-out cho
+// client của mình. Seq vẫn do từng Hub tự đánh nên ?since= chỉ có nghĩa
+// khi client reconnect lại đúng replica cũ; sai replica sẽ nhận RESYNC_REQUIRED
+// hoặc replay theo seq của replica mới.
+type Backplane interface {
+	Publish(ctx context.Context, data []byte) error
+	// Subscribe trả về channel nhận event đã publish, đóng khi ctx bị huỷ.
+	Subscribe(ctx context.Context) (<-chan []byte, error)
+	Close() error
+}
+
+var ErrBackplaneClosed = errors.New("backplane closed")
+
+// WithBackplane bật chế độ cluster-wide cho Hub.
+func WithBackplane(bp Backplane) Option {
+	return func(h *Hub) { h.backplane = bp }
+}
+
+// remoteEvent là WSEvent nhận từ backplane, giữ nguyên payload dạng JSON.
+type remoteEvent struct {
+	Type      EventType       `json:"type"`
+	Payload   json.RawMessage `json:"payload"`
+	Timestamp json.RawMessage `json:"timestamp"`
+}
+
+// subscribeBackplane gọi trong NewHub, trước khi có Broadcast nào, để không
+// có event nào được publish khi Hub chưa lắng nghe.
+func (h *Hub) subscribeBackplane() {
+	if h.backplane == nil {
+		return
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	remote, err := h.backplane.Subscribe(ctx)
+	if err != nil {
+		cancel()
+		log.Printf("[WS] Backplane subscribe error, falling back to local hub: %v", err)
+		h.backplane = nil
+		return
+	}
+	h.remote = remote
+	h.stopRemote = cancel
+}
+
+// receiveRemote đưa event từ backplane vào cùng hàng đợi với Broadcast, nên
+// overflow policy áp dụng cả cho event của replica khác: drop/block hết giờ
+// thì event bị bỏ và được đếm trong BroadcastDropped, spill thì ghi vào spill.
+// Đọc backplane liên tục để buffer của Redis PubSub không đầy rồi âm thầm
+// bỏ message. Chạy tới khi channel của backplane đóng hoặc hub dừng.
+func (h *Hub) receiveRemote() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-h.quit:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	for data := range h.remote {
+		event, ok := decodeRemote(data)
+		if !ok {
+			continue
+		}
+		if err := h.enqueue(ctx, event); err != nil && ctx.Err() != nil {
+			return
+		}
+	}
+}
+
+func decodeRemote(data []byte) (WSEvent, bool) {
+	var re remoteEvent
+	if err := json.Unmarshal(data, &re); err != nil {
+		log.Printf("[WS] Backplane decode error: %v", err)
+		return WSEvent{}, false
+	}
+	event := WSEvent{Type: re.Type, Payload: re.Payload}
+	if err := json.Unmarshal(re.Timestamp, &event.Timestamp); err != nil {
+		log.Printf("[WS] Backplane decode error: %v", err)
+		return WSEvent{}, false
+	}
+	return event, true
+}
+
+// MemoryBackplane nối nhiều Hub trong cùng process, dùng cho test và dev.
+type MemoryBackplane struct {
+	mu     sync.RWMutex
+	subs   map[chan []byte]struct{}
+	closed bool
+}
+
+func NewMemoryBackplane() *MemoryBackplane {
+	return &MemoryBackplane{subs: make(map[chan []byte]struct{})}
+}
+
+func (b *MemoryBackplane) Publish(ctx context.Context, data []byte) error {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return ErrBackplaneClosed
+	}
+	for ch := range b.subs {
+		select {
+		case ch <- data:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBackplane) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil, ErrBackplaneClosed
+	}
+
+	ch := make(chan []byte, 256)
+	b.subs[ch] = struct{}{}
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+		b.mu.Unlock()
+	}()
+	return ch, nil
+}
+
+func (b *MemoryBackplane) Close() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		return nil
+	}
+	b.closed = true
+	for ch := range b.subs {
+		delete(b.subs, ch)
+		close(ch)
+	}
+	return nil
+}
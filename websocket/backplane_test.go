@@ -0,0 +1,153 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/gorilla/websocket"
+	"github.com/redis/go-redis/v9"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// testBackplaneAcrossHubs mô phỏng hai replica dùng chung một backplane:
+// client ở replica B phải nhận được event Broadcast từ replica A.
+func testBackplaneAcrossHubs(t *testing.T, newBackplane func() Backplane) {
+	hubA := NewHub(WithBackplane(newBackplane()))
+	hubB := NewHub(WithBackplane(newBackplane()))
+	go hubA.Run()
+	go hubB.Run()
+
+	connA := dial(t, newTestServer(t, hubA), "")
+	connB := dial(t, newTestServer(t, hubB), "")
+	waitForClients(t, hubA, 1)
+	waitForClients(t, hubB, 1)
+
+	hubA.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "a1", Name: "from A"})
+	hubB.Broadcast(context.Background(), EventItemUpdated, &domain.Item{ID: "b1", Name: "from B"})
+
+	for _, conn := range []*websocket.Conn{connA, connB} {
+		got := map[EventType]bool{}
+		for i := 0; i < 2; i++ {
+			got[readEvent(t, conn).Type] = true
+		}
+		if !got[EventItemCreated] || !got[EventItemUpdated] {
+			t.Fatalf("client missed cluster events, got %v", got)
+		}
+	}
+}
+
+func TestMemoryBackplane(t *testing.T) {
+	bp := NewMemoryBackplane()
+	t.Cleanup(func() { bp.Close() })
+	testBackplaneAcrossHubs(t, func() Backplane { return bp })
+}
+
+func TestRedisBackplane(t *testing.T) {
+	mr := miniredis.RunT(t)
+	testBackplaneAcrossHubs(t, func() Backplane {
+		client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+		t.Cleanup(func() { client.Close() })
+		return NewRedisBackplane(client, "hub:events")
+	})
+}
+
+func TestRemotePayloadIsRoutable(t *testing.T) {
+	bp := NewMemoryBackplane()
+	t.Cleanup(func() { bp.Close() })
+	hub := NewHub(WithBackplane(bp))
+	go hub.Run()
+
+	conn := dial(t, newTestServer(t, hub), "")
+	if err := conn.WriteJSON(clientMessage{Type: "subscribe", ItemID: "keep"}); err != nil {
+		t.Fatalf("subscribe: %v", err)
+	}
+	readEvent(t, conn)
+
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "skip"})
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "keep"})
+
+	ev := readEvent(t, conn)
+	payload, _ := ev.Payload.(map[string]interface{})
+	if payload["id"] != "keep" {
+		t.Fatalf("got payload %v, want item keep", ev.Payload)
+	}
+}
+
+// Event từ backplane đi qua overflow policy của hub nhận: Run bị kẹt (giữ
+// hub.mu) trong khi replica khác publish nhiều hơn sức chứa hàng đợi.
+func TestRedisBackplaneOverflow(t *testing.T) {
+	const n = 400
+	for _, policy := range []OverflowPolicy{OverflowDrop, OverflowBlock, OverflowSpill} {
+		t.Run(string(policy), func(t *testing.T) {
+			mr := miniredis.RunT(t)
+			newClient := func() *redis.Client {
+				client := redis.NewClient(&redis.Options{Addr: mr.Addr()})
+				t.Cleanup(func() { client.Close() })
+				return client
+			}
+			hub := NewHub(
+				WithBackplane(NewRedisBackplane(newClient(), "hub:events")),
+				WithOverflowPolicy(policy, 20*time.Millisecond),
+				WithSpill(NewRedisSpill(newClient(), "hub:spill")),
+				WithReplayBuffer(n),
+			)
+			go hub.Run()
+			defer hub.Shutdown(context.Background())
+			publisher := NewRedisBackplane(newClient(), "hub:events")
+
+			hub.mu.Lock()
+			for i := 0; i < n; i++ {
+				data, _ := json.Marshal(WSEvent{Type: EventItemCreated, Payload: i, Timestamp: time.Now()})
+				if err := publisher.Publish(context.Background(), data); err != nil {
+					hub.mu.Unlock()
+					t.Fatalf("publish #%d: %v", i, err)
+				}
+			}
+			// chờ tới khi policy đã phải xử lý hàng đợi đầy
+			overflowed := func() bool {
+				if policy == OverflowSpill {
+					return mr.Exists("hub:spill")
+				}
+				return hub.BroadcastDropped() > 0
+			}
+			deadline := time.Now().Add(2 * time.Second)
+			for !overflowed() && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			hub.mu.Unlock()
+			if !overflowed() {
+				t.Fatalf("queue never overflowed: dropped %d", hub.BroadcastDropped())
+			}
+
+			// mọi event hoặc được phát hoặc được đếm là bị bỏ, không mất âm thầm
+			deadline = time.Now().Add(5 * time.Second)
+			for hub.LastSeq("")+hub.BroadcastDropped() < n && time.Now().Before(deadline) {
+				time.Sleep(5 * time.Millisecond)
+			}
+			delivered, dropped := hub.LastSeq(""), hub.BroadcastDropped()
+			if delivered+dropped != n {
+				t.Fatalf("delivered %d + dropped %d, want %d", delivered, dropped, n)
+			}
+			if policy == OverflowSpill {
+				if dropped != 0 {
+					t.Fatalf("spill dropped %d event(s)", dropped)
+				}
+				// history chỉ được ghi trong Run, đọc sau khi Run đã fan-out xong mọi event
+				waitForFanOut(t, hub, n)
+				for i, ev := range hub.roomFor("").history {
+					var got struct{ Payload int }
+					if err := json.Unmarshal(ev.data, &got); err != nil {
+						t.Fatal(err)
+					}
+					if got.Payload != i {
+						t.Fatalf("event #%d has payload %d, want %d (order broken)", ev.seq, got.Payload, i)
+					}
+				}
+			}
+		})
+	}
+}
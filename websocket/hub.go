@@ -1,111 +1,447 @@
 package websocket
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
 	"sync"
+	"sync/atomic"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+
+	"github.com/JIeeiroSst/hub/domain"
 )
 
+// CloseTooManyConnections là close code gửi cho kết nối vượt giới hạn số
+// kết nối của một identity.
+const CloseTooManyConnections = 4429
+
+// ErrTooManyConnections: identity đã dùng hết số kết nối cho phép.
+var ErrTooManyConnections = fmt.Errorf("%w: too many connections", domain.ErrRateLimited)
+
 type EventType string
 
 const (
-	EventItemCreated EventType = "ITEM_CREATED"
-	EventItemUpdated EventType = "ITEM_UPDATED"
-	EventItemDeleted EventType = "ITEM_DELETED"
+	EventItemCreated EventType = domain.EventItemCreated
+	EventItemUpdated EventType = domain.EventItemUpdated
+	EventItemDeleted EventType = domain.EventItemDeleted
+
+	// EventResyncRequired báo client rằng không thể replay các event đã lỡ,
+	// client cần tải lại list qua REST rồi tiếp tục từ Seq của event này.
+	EventResyncRequired EventType = "RESYNC_REQUIRED"
+
+	// Phản hồi cho frame subscribe/unsubscribe của client, không có seq.
+	EventSubscribed   EventType = "SUBSCRIBED"
+	EventUnsubscribed EventType = "UNSUBSCRIBED"
+	EventError        EventType = "ERROR"
 )
 
 type WSEvent struct {
+	Seq       uint64      `json:"seq"`
 	Type      EventType   `json:"type"`
 	Payload   interface{} `json:"payload"`
 	Timestamp time.Time   `json:"timestamp"`
 }
 
 type Client struct {
-	id   string
-	conn *websocket.Conn
-	send chan []byte
-	hub  *Hub
-	mu   sync.Mutex
+	id    string
+	conn  *websocket.Conn
+	send  chan []byte
+	hub   *Hub
+	mu    sync.Mutex
+	since *uint64
+	// scope giới hạn item client được nhận event, rỗng nghĩa là mọi item
+	scope domain.Scope
+	// identity dùng để đếm số kết nối, xem WithMaxConnsPerIdentity
+	identity string
+	// closeFrame được gửi thay cho close frame rỗng khi hub từ chối hoặc
+	// đóng client; chỉ ghi trước khi close(send)
+	closeFrame []byte
+	// flushed đóng khi WritePump kết thúc (đã gửi close frame hoặc lỗi)
+	flushed chan struct{}
+
+	// dropped đếm event bị bỏ do client đọc chậm; lagging chỉ dùng trong Run
+	dropped atomic.Uint64
+	lagging bool
+
+	// subs chỉ được đọc/ghi trong goroutine Run; rỗng nghĩa là nhận mọi event
+	subs map[string]*subscription
+}
+
+// room gom client, seq và lịch sử replay của một tenant. Event chỉ được gửi
+// trong room của tenant sở hữu item nên không bao giờ lọt sang tenant khác;
+// seq cũng đánh riêng theo room để không lộ số lượng event của tenant khác.
+type room struct {
+	clients map[*Client]bool
+
+	// seq và history chỉ được ghi trong goroutine Run
+	seq     atomic.Uint64
+	history []replayEvent
 }
 
 type Hub struct {
-	clients    map[*Client]bool
-	broadcast  chan []byte
+	// rooms theo tenant ID, "" là room của item không thuộc tenant nào
+	rooms      map[string]*room
+	broadcast  chan WSEvent
 	register   chan *Client
 	unregister chan *Client
+	inbound    chan clientRequest
 	mu         sync.RWMutex
+	clients    atomic.Int64
+
+	// processed đếm event Run đã fan-out xong, để test và benchmark biết Run
+	// đã theo kịp Broadcast chưa
+	processed atomic.Uint64
+
+	backplane  Backplane
+	remote     <-chan []byte
+	stopRemote context.CancelFunc
+
+	replaySize int
+	maxSubs    int
+
+	// conns đếm kết nối theo identity, được bảo vệ bởi connMu (không phải mu)
+	// để Admit không tranh lock với fan-out
+	conns    map[string]int
+	connMu   sync.Mutex
+	maxConns int
+
+	slowPolicy SlowConsumerPolicy
+	dropped    atomic.Uint64
+
+	overflow         OverflowPolicy
+	overflowTimeout  time.Duration
+	spill            Spill
+	spillState       spillState
+	broadcastDropped atomic.Uint64
+
+	// quit yêu cầu Run dừng; done đóng khi Run đã đóng mọi client và thoát.
+	// closed là các client bị đóng lúc shutdown, chỉ đọc sau khi done đóng.
+	quit     chan struct{}
+	quitOnce sync.Once
+	done     chan struct{}
+	closed   []*Client
+}
+
+type Option func(*Hub)
+
+// WithReplayBuffer đặt số event gần nhất được giữ lại để replay khi client reconnect.
+func WithReplayBuffer(n int) Option {
+	return func(h *Hub) { h.replaySize = n }
+}
+
+// WithMaxConnsPerIdentity giới hạn số kết nối đồng thời (WebSocket và SSE)
+// của một identity; n <= 0 là không giới hạn.
+func WithMaxConnsPerIdentity(n int) Option {
+	return func(h *Hub) { h.maxConns = n }
+}
+
+// WithMaxSubscriptions giới hạn số subscription của một client; n <= 0 là
+// không giới hạn.
+func WithMaxSubscriptions(n int) Option {
+	return func(h *Hub) { h.maxSubs = n }
 }
 
-func NewHub() *Hub {
-	return &Hub{
-		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+func NewHub(opts ...Option) *Hub {
+	h := &Hub{
+		rooms:      make(map[string]*room),
+		broadcast:  make(chan WSEvent, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
+		inbound:    make(chan clientRequest),
+		replaySize: 256,
+		maxSubs:    defaultMaxSubscriptions,
+		conns:      make(map[string]int),
+		slowPolicy: SlowConsumerDisconnect,
+		quit:       make(chan struct{}),
+		done:       make(chan struct{}),
+
+		overflow:        OverflowBlock,
+		overflowTimeout: defaultOverflowTimeout,
 	}
+	for _, opt := range opts {
+		opt(h)
+	}
+	if h.overflow == OverflowSpill && h.spill == nil {
+		log.Printf("[WS] Overflow policy spill needs WithSpill, falling back to block")
+		h.overflow = OverflowBlock
+	}
+	// spill có thể còn event từ lần chạy trước, phát hết chúng trước event mới
+	h.spillState.spilling = h.overflow == OverflowSpill
+	h.spillState.wake = make(chan struct{}, 1)
+	h.subscribeBackplane()
+	return h
 }
 
+// Run xử lý đăng ký và fan-out tới khi Shutdown được gọi.
 func (h *Hub) Run() {
+	defer close(h.done)
+	if h.overflow == OverflowSpill {
+		go h.drainSpill()
+	}
+	if h.remote != nil {
+		go h.receiveRemote()
+	}
 	for {
 		select {
+		case <-h.quit:
+			h.closeAll()
+			return
+
 		case client := <-h.register:
+			if !h.acquire(client.identity) {
+				log.Printf("[WS] Rejected %s: %v", client.identity, ErrTooManyConnections)
+				client.closeFrame = websocket.FormatCloseMessage(CloseTooManyConnections, "too many connections")
+				close(client.send)
+				continue
+			}
+			r := h.roomFor(client.scope.TenantID)
 			h.mu.Lock()
-			h.clients[client] = true
+			r.clients[client] = true
 			h.mu.Unlock()
-			log.Printf("[WS] Client connected. Total: %d", len(h.clients))
+			total := h.clients.Add(1)
+			if client.since != nil {
+				h.replayTo(r, client, *client.since)
+			}
+			log.Printf("[WS] Client connected. Total: %d", total)
+
+		case req := <-h.inbound:
+			// chỉ Run sửa rooms và map client nên đọc ở đây không cần lock
+			if r := h.rooms[req.client.scope.TenantID]; r != nil && r.clients[req.client] {
+				h.handleMessage(r, req.client, req.msg)
+			}
 
 		case client := <-h.unregister:
-			h.mu.Lock()
-			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
+			// client đã bị ngắt vì đọc chậm thì không còn trong room, không đóng lại lần nữa
+			if h.remove(client) {
 				close(client.send)
 			}
-			h.mu.Unlock()
-			log.Printf("[WS] Client disconnected. Total: %d", len(h.clients))
-
-		case message := <-h.broadcast:
-			h.mu.RLock()
-			for client := range h.clients {
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+			log.Printf("[WS] Client disconnected. Total: %d", h.clients.Load())
+
+		case event := <-h.broadcast:
+			h.fanOut(event)
+			h.processed.Add(1)
+		}
+	}
+}
+
+// closeAll gửi close frame going-away cho mọi client và dừng nhận event từ
+// backplane. Chạy trong goroutine Run.
+func (h *Hub) closeAll() {
+	if h.stopRemote != nil {
+		h.stopRemote()
+	}
+	goingAway := websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+
+	h.mu.Lock()
+	for _, r := range h.rooms {
+		for client := range r.clients {
+			client.closeFrame = goingAway
+			close(client.send)
+			h.closed = append(h.closed, client)
+		}
+		r.clients = make(map[*Client]bool)
+	}
+	h.mu.Unlock()
+	h.clients.Add(-int64(len(h.closed)))
+
+	h.connMu.Lock()
+	h.conns = make(map[string]int)
+	h.connMu.Unlock()
+	log.Printf("[WS] Shutdown: closed %d client(s)", len(h.closed))
+}
+
+// Shutdown dừng Run, gửi close frame going-away (1001) cho mọi client rồi chờ
+// các WritePump gửi xong. Hết ctx thì đóng thẳng các kết nối còn lại.
+// Client đăng ký sau khi hub đã dừng bị đóng ngay với cùng close frame.
+func (h *Hub) Shutdown(ctx context.Context) error {
+	h.quitOnce.Do(func() { close(h.quit) })
+	select {
+	case <-h.done:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+
+	for i, c := range h.closed {
+		if c.conn == nil {
+			continue
+		}
+		select {
+		case <-c.flushed:
+		case <-ctx.Done():
+			for _, rest := range h.closed[i:] {
+				if rest.conn != nil {
+					rest.conn.Close()
 				}
 			}
-			h.mu.RUnlock()
+			return ctx.Err()
 		}
 	}
+	return nil
 }
 
-func (h *Hub) Broadcast(eventType EventType, payload interface{}) {
+// room trả về room của tenant, tạo mới nếu chưa có. Phải giữ h.mu (ghi).
+func (h *Hub) room(tenantID string) *room {
+	r, ok := h.rooms[tenantID]
+	if !ok {
+		r = &room{clients: make(map[*Client]bool)}
+		h.rooms[tenantID] = r
+	}
+	return r
+}
+
+// roomFor như room nhưng tự lấy lock.
+func (h *Hub) roomFor(tenantID string) *room {
+	h.mu.RLock()
+	r, ok := h.rooms[tenantID]
+	h.mu.RUnlock()
+	if ok {
+		return r
+	}
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return h.room(tenantID)
+}
+
+// remove bỏ client khỏi room, trả về false nếu client không còn trong hub.
+// Map client chỉ bị sửa trong goroutine Run, luôn dưới h.mu (ghi) để
+// ClientStats đọc song song không bị race.
+func (h *Hub) remove(c *Client) bool {
+	r := h.rooms[c.scope.TenantID]
+	if r == nil || !r.clients[c] {
+		return false
+	}
+	h.mu.Lock()
+	delete(r.clients, c)
+	h.mu.Unlock()
+	h.clients.Add(-1)
+	h.release(c.identity)
+	return true
+}
+
+// acquire giữ một slot kết nối cho identity, false khi đã hết slot.
+func (h *Hub) acquire(identity string) bool {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	if h.maxConns > 0 && h.conns[identity] >= h.maxConns {
+		return false
+	}
+	h.conns[identity]++
+	return true
+}
+
+// release trả lại slot kết nối của identity.
+func (h *Hub) release(identity string) {
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	if h.conns[identity] <= 1 {
+		delete(h.conns, identity)
+		return
+	}
+	h.conns[identity]--
+}
+
+// Admit kiểm tra trước (khi còn trả được HTTP 429) identity có được mở thêm
+// kết nối không. Giới hạn vẫn được áp dụng chặt lúc đăng ký trong Run.
+func (h *Hub) Admit(identity string) error {
+	if h.maxConns <= 0 {
+		return nil
+	}
+	h.connMu.Lock()
+	defer h.connMu.Unlock()
+	if h.conns[identity] >= h.maxConns {
+		return ErrTooManyConnections
+	}
+	return nil
+}
+
+// fanOut chạy trong goroutine Run: gán seq và marshal event đúng một lần,
+// mọi client của room dùng chung bản đã marshal. Chỉ Run sửa map client nên
+// duyệt r.clients ở đây không cần lock; client bị ngắt được gỡ (dưới lock)
+// sau vòng lặp rồi mới đóng send, nên mỗi channel chỉ bị đóng đúng một lần.
+func (h *Hub) fanOut(event WSEvent) {
+	meta := newEventMeta(event)
+	r := h.roomFor(meta.tenantID)
+	message, ok := h.record(r, event, meta)
+	if !ok {
+		return
+	}
+	seq := r.seq.Load()
+
+	var slow []*Client
+	for client := range r.clients {
+		if client.matches(meta) && !h.deliver(client, message, seq) {
+			slow = append(slow, client)
+		}
+	}
+	for _, client := range slow {
+		if h.remove(client) {
+			close(client.send)
+		}
+	}
+}
+
+// Broadcast phát event tới client. Khi có backplane, event đi vòng qua
+// backplane để tới mọi replica (kể cả replica này); publish lỗi thì vẫn
+// phát cho client local. Không block quá ctx và overflow policy của hub;
+// lỗi trả về bọc domain.ErrUnavailable. Với backplane, Broadcast chỉ báo lỗi
+// publish; overflow policy được áp dụng ở mỗi replica lúc nhận event về.
+func (h *Hub) Broadcast(ctx context.Context, eventType EventType, payload interface{}) error {
 	event := WSEvent{
 		Type:      eventType,
 		Payload:   payload,
 		Timestamp: time.Now(),
 	}
-	data, err := json.Marshal(event)
-	if err != nil {
-		log.Printf("[WS] Marshal error: %v", err)
-		return
+
+	if h.backplane != nil {
+		data, err := json.Marshal(event)
+		if err == nil {
+			err = h.backplane.Publish(ctx, data)
+		}
+		if err == nil {
+			return nil
+		}
+		log.Printf("[WS] Backplane publish error, delivering locally: %v", err)
 	}
-	h.broadcast <- data
+
+	return h.enqueue(ctx, event)
 }
 
-func (h *Hub) ClientCount() int {
+// BroadcastLocal chỉ phát cho client của replica này, không qua backplane.
+// Dùng cho nguồn event mà mọi replica đều tự nhận được (change feed), nếu
+// không mỗi event sẽ tới client nhiều lần.
+func (h *Hub) BroadcastLocal(ctx context.Context, eventType EventType, payload interface{}) error {
+	return h.enqueue(ctx, WSEvent{
+		Type:      eventType,
+		Payload:   payload,
+		Timestamp: time.Now(),
+	})
+}
+
+// LastSeq trả về seq của event mới nhất đã phát trong room của tenant,
+// client dùng làm ?since=.
+func (h *Hub) LastSeq(tenantID string) uint64 {
 	h.mu.RLock()
 	defer h.mu.RUnlock()
-	return len(h.clients)
+	if r, ok := h.rooms[tenantID]; ok {
+		return r.seq.Load()
+	}
+	return 0
+}
+
+func (h *Hub) ClientCount() int {
+	return int(h.clients.Load())
 }
 
 func (c *Client) WritePump() {
-	ticker := time.NewTicker(30 * time.Second) 
+	ticker := time.NewTicker(30 * time.Second)
 	defer func() {
 		ticker.Stop()
 		c.conn.Close()
+		close(c.flushed)
 	}()
 
 	for {
@@ -113,7 +449,11 @@ func (c *Client) WritePump() {
 		case message, ok := <-c.send:
 			c.conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
 			if !ok {
-				c.conn.WriteMessage(websocket.CloseMessage, []byte{})
+				frame := c.closeFrame
+				if frame == nil {
+					frame = []byte{}
+				}
+				c.conn.WriteMessage(websocket.CloseMessage, frame)
 				return
 			}
 			if err := c.conn.WriteMessage(websocket.TextMessage, message); err != nil {
@@ -131,7 +471,7 @@ func (c *Client) WritePump() {
 
 func (c *Client) ReadPump() {
 	defer func() {
-		c.hub.unregister <- c
+		c.hub.leave(c)
 		c.conn.Close()
 	}()
 
@@ -143,22 +483,55 @@ func (c *Client) ReadPump() {
 	})
 
 	for {
-		_, _, err := c.conn.ReadMessage()
+		_, data, err := c.conn.ReadMessage()
 		if err != nil {
 			if websocket.IsUnexpectedCloseError(err, websocket.CloseGoingAway, websocket.CloseAbnormalClosure) {
 				log.Printf("[WS] Read error: %v", err)
 			}
 			break
 		}
+
+		var msg clientMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			continue
+		}
+		select {
+		case c.hub.inbound <- clientRequest{client: c, msg: msg}:
+		case <-c.hub.done:
+			return
+		}
+	}
+}
+
+// leave huỷ đăng ký client; hub đã dừng thì client đã được đóng sẵn.
+func (h *Hub) leave(c *Client) {
+	select {
+	case h.unregister <- c:
+	case <-h.done:
 	}
 }
 
-func NewClient(hub *Hub, conn *websocket.Conn) *Client {
+// NewClient đăng ký client vào hub. since khác nil nghĩa là client đang
+// reconnect và muốn nhận lại các event có Seq > *since. Client chỉ nhận event
+// của item nằm trong scope. Khi identity đã đủ số kết nối, hub đóng client
+// với close code CloseTooManyConnections.
+func NewClient(hub *Hub, conn *websocket.Conn, since *uint64, scope domain.Scope, identity string) *Client {
 	client := &Client{
-		conn: conn,
-		send: make(chan []byte, 256),
-		hub:  hub,
+		id:       uuid.NewString(),
+		conn:     conn,
+		send:     make(chan []byte, 256),
+		hub:      hub,
+		since:    since,
+		scope:    scope,
+		identity: identity,
+		subs:     make(map[string]*subscription),
+		flushed:  make(chan struct{}),
+	}
+	select {
+	case hub.register <- client:
+	case <-hub.done:
+		client.closeFrame = websocket.FormatCloseMessage(websocket.CloseGoingAway, "server shutting down")
+		close(client.send)
 	}
-	hub.register <- client
 	return client
 }
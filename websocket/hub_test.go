@@ -0,0 +1,473 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// newTestServer dựng một endpoint /ws tối giản giống handler.WebSocket.
+func newTestServer(t *testing.T, hub *Hub) *httptest.Server {
+	t.Helper()
+	upgrader := websocket.Upgrader{CheckOrigin: func(r *http.Request) bool { return true }}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		var since *uint64
+		if v, err := strconv.ParseUint(r.URL.Query().Get("since"), 10, 64); err == nil {
+			since = &v
+		}
+		// ?owner=&tenant=&identity= thay cho principal đã xác thực
+		scope := domain.Scope{OwnerID: r.URL.Query().Get("owner"), TenantID: r.URL.Query().Get("tenant")}
+		client := NewClient(hub, conn, since, scope, r.URL.Query().Get("identity"))
+		go client.WritePump()
+		go client.ReadPump()
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func dial(t *testing.T, srv *httptest.Server, query string) *websocket.Conn {
+	t.Helper()
+	url := "ws" + strings.TrimPrefix(srv.URL, "http") + "/ws" + query
+	conn, _, err := websocket.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func readEvent(t *testing.T, conn *websocket.Conn) WSEvent {
+	t.Helper()
+	conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var ev WSEvent
+	if err := conn.ReadJSON(&ev); err != nil {
+		t.Fatalf("read: %v", err)
+	}
+	return ev
+}
+
+func waitForSeq(t *testing.T, hub *Hub, seq uint64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.LastSeq("") < seq {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub did not reach seq %d (at %d)", seq, hub.LastSeq(""))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+// waitForFanOut chờ Run fan-out xong n event đầu tiên.
+func waitForFanOut(t *testing.T, hub *Hub, n uint64) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.processed.Load() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub did not fan out %d event(s)", n)
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func waitForClients(t *testing.T, hub *Hub, n int) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.ClientCount() < n {
+		if time.Now().After(deadline) {
+			t.Fatalf("hub has %d clients, want %d", hub.ClientCount(), n)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHubAssignsIncreasingSeq(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := newTestServer(t, hub)
+	conn := dial(t, srv, "")
+
+	waitForClients(t, hub, 1)
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(context.Background(), EventItemCreated, i)
+	}
+	for want := uint64(1); want <= 3; want++ {
+		if ev := readEvent(t, conn); ev.Seq != want {
+			t.Fatalf("seq = %d, want %d", ev.Seq, want)
+		}
+	}
+}
+
+func TestHubMarshalsEventOnce(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+
+	clients := make([]*Client, 10)
+	for i := range clients {
+		clients[i] = NewStreamClient(hub, nil, domain.Scope{}, fmt.Sprintf("c%d", i))
+	}
+	waitForClients(t, hub, len(clients))
+
+	hub.Broadcast(context.Background(), EventItemCreated, "x")
+	waitForFanOut(t, hub, 1)
+
+	// mọi client dùng chung một bản marshal của event
+	first := <-clients[0].send
+	for i, c := range clients[1:] {
+		if data := <-c.send; &data[0] != &first[0] {
+			t.Fatalf("client %d: event was marshaled again", i+1)
+		}
+	}
+}
+
+func TestHubReplaysMissedEvents(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	for i := 0; i < 5; i++ {
+		hub.Broadcast(context.Background(), EventItemCreated, i)
+	}
+	waitForSeq(t, hub, 5)
+
+	conn := dial(t, srv, "?since=2")
+	for want := uint64(3); want <= 5; want++ {
+		ev := readEvent(t, conn)
+		if ev.Seq != want || ev.Type != EventItemCreated {
+			t.Fatalf("got %s #%d, want %s #%d", ev.Type, ev.Seq, EventItemCreated, want)
+		}
+	}
+
+	hub.Broadcast(context.Background(), EventItemDeleted, "live")
+	if ev := readEvent(t, conn); ev.Seq != 6 {
+		t.Fatalf("live event seq = %d, want 6", ev.Seq)
+	}
+}
+
+func TestHubResyncWhenGapTooOld(t *testing.T) {
+	hub := NewHub(WithReplayBuffer(2))
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	for i := 0; i < 5; i++ {
+		hub.Broadcast(context.Background(), EventItemCreated, i)
+	}
+	waitForSeq(t, hub, 5)
+
+	conn := dial(t, srv, "?since=1")
+	ev := readEvent(t, conn)
+	if ev.Type != EventResyncRequired || ev.Seq != 5 {
+		t.Fatalf("got %s #%d, want %s #5", ev.Type, ev.Seq, EventResyncRequired)
+	}
+}
+
+func TestHubResumeMessage(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	for i := 0; i < 3; i++ {
+		hub.Broadcast(context.Background(), EventItemCreated, i)
+	}
+	waitForSeq(t, hub, 3)
+
+	conn := dial(t, srv, "")
+	if err := conn.WriteJSON(clientMessage{Type: "resume", Since: 1}); err != nil {
+		t.Fatalf("write resume: %v", err)
+	}
+	for want := uint64(2); want <= 3; want++ {
+		if ev := readEvent(t, conn); ev.Seq != want {
+			t.Fatalf("seq = %d, want %d", ev.Seq, want)
+		}
+	}
+}
+
+func TestHubRoutesBySubscription(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	byItem := dial(t, srv, "")
+	byType := dial(t, srv, "")
+	byName := dial(t, srv, "")
+	firehose := dial(t, srv, "")
+
+	subscribe := func(conn *websocket.Conn, msg clientMessage) {
+		t.Helper()
+		msg.Type = "subscribe"
+		if err := conn.WriteJSON(msg); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+		if ev := readEvent(t, conn); ev.Type != EventSubscribed {
+			t.Fatalf("got %s, want %s", ev.Type, EventSubscribed)
+		}
+	}
+	subscribe(byItem, clientMessage{ItemID: "b"})
+	subscribe(byType, clientMessage{EventType: EventItemDeleted})
+	subscribe(byName, clientMessage{Name: "report-*"})
+	waitForClients(t, hub, 4)
+
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "a", Name: "Report-2024"})
+	hub.Broadcast(context.Background(), EventItemUpdated, &domain.Item{ID: "b", Name: "notes"})
+	hub.Broadcast(context.Background(), EventItemDeleted, map[string]string{"id": "c", "name": "draft"})
+
+	expect := func(label string, conn *websocket.Conn, want ...uint64) {
+		t.Helper()
+		for _, seq := range want {
+			if ev := readEvent(t, conn); ev.Seq != seq {
+				t.Fatalf("%s: got seq %d, want %d", label, ev.Seq, seq)
+			}
+		}
+	}
+	expect("item", byItem, 2)
+	expect("type", byType, 3)
+	expect("name", byName, 1)
+	expect("firehose", firehose, 1, 2, 3)
+
+	// hết subscription thì quay về nhận mọi event
+	if err := byItem.WriteJSON(clientMessage{Type: "unsubscribe", ItemID: "b"}); err != nil {
+		t.Fatalf("unsubscribe: %v", err)
+	}
+	if ev := readEvent(t, byItem); ev.Type != EventUnsubscribed {
+		t.Fatalf("got %s, want %s", ev.Type, EventUnsubscribed)
+	}
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "z"})
+	expect("item after unsubscribe", byItem, 4)
+}
+
+func TestHubLimitsSubscriptionsPerClient(t *testing.T) {
+	hub := NewHub(WithMaxSubscriptions(2))
+	go hub.Run()
+	srv := newTestServer(t, hub)
+	conn := dial(t, srv, "")
+
+	subscribe := func(id string, want EventType) {
+		t.Helper()
+		if err := conn.WriteJSON(clientMessage{Type: "subscribe", ID: id, ItemID: id}); err != nil {
+			t.Fatalf("subscribe: %v", err)
+		}
+		if ev := readEvent(t, conn); ev.Type != want {
+			t.Fatalf("subscribe %s: got %s, want %s", id, ev.Type, want)
+		}
+	}
+	subscribe("a", EventSubscribed)
+	subscribe("b", EventSubscribed)
+	subscribe("c", EventError)
+	// thay điều kiện của subscription đã có vẫn được
+	subscribe("a", EventSubscribed)
+
+	// subscription bị từ chối không được thêm: event của "c" không tới client
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "c"})
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "b"})
+	if ev := readEvent(t, conn); ev.Seq != 2 {
+		t.Fatalf("got seq %d, want 2", ev.Seq)
+	}
+}
+
+func TestHubDeliversOnlyVisibleItems(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	alice := dial(t, srv, "?owner=alice")
+	acme := dial(t, srv, "?owner=carol&tenant=acme")
+	admin := dial(t, srv, "")
+	waitForClients(t, hub, 3)
+
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "1", OwnerID: "alice"})
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "2", OwnerID: "bob", TenantID: "acme"})
+	hub.Broadcast(context.Background(), EventItemDeleted, map[string]string{"id": "1", "owner_id": "alice"})
+	hub.Broadcast(context.Background(), EventItemUpdated, json.RawMessage(`{"id":"3","owner_id":"bob"}`))
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "4", OwnerID: "alice"})
+
+	expect := func(label string, conn *websocket.Conn, want ...uint64) {
+		t.Helper()
+		for _, seq := range want {
+			if ev := readEvent(t, conn); ev.Seq != seq {
+				t.Fatalf("%s: got seq %d, want %d", label, ev.Seq, seq)
+			}
+		}
+	}
+	expect("owner", alice, 1, 2, 4)
+	expect("tenant", acme, 1)
+	// client không có tenant chỉ ở room "", không thấy event của acme
+	expect("unscoped", admin, 1, 2, 3, 4)
+
+	// replay cũng phải lọc theo scope
+	replay := dial(t, srv, "?owner=alice&since=0")
+	expect("replay", replay, 1, 2, 4)
+}
+
+func TestHubIsolatesTenantRooms(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	acme := dial(t, srv, "?tenant=acme")
+	globex := dial(t, srv, "?tenant=globex")
+	waitForClients(t, hub, 2)
+
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "a1", TenantID: "acme"})
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "g1", TenantID: "globex"})
+	hub.Broadcast(context.Background(), EventItemCreated, &domain.Item{ID: "a2", TenantID: "acme"})
+
+	for _, want := range []uint64{1, 2} {
+		if ev := readEvent(t, acme); ev.Seq != want {
+			t.Fatalf("acme: got seq %d, want %d", ev.Seq, want)
+		}
+	}
+	ev := readEvent(t, globex)
+	if ev.Seq != 1 {
+		t.Fatalf("globex: got seq %d, want 1", ev.Seq)
+	}
+	if p, _ := ev.Payload.(map[string]interface{}); p["id"] != "g1" {
+		t.Fatalf("globex: got payload %v, want item g1", ev.Payload)
+	}
+	if got := hub.LastSeq("acme"); got != 2 {
+		t.Fatalf("LastSeq(acme) = %d, want 2", got)
+	}
+	if got := hub.LastSeq("initech"); got != 0 {
+		t.Fatalf("LastSeq(initech) = %d, want 0", got)
+	}
+
+	// replay chỉ lấy từ history của room mình
+	late := dial(t, srv, "?tenant=globex&since=0")
+	if ev := readEvent(t, late); ev.Seq != 1 {
+		t.Fatalf("globex replay: got seq %d, want 1", ev.Seq)
+	}
+	late.SetReadDeadline(time.Now().Add(100 * time.Millisecond))
+	if err := late.ReadJSON(&ev); err == nil {
+		t.Fatalf("globex replay: unexpected event %+v", ev)
+	}
+}
+
+func TestHubLimitsConnectionsPerIdentity(t *testing.T) {
+	hub := NewHub(WithMaxConnsPerIdentity(2))
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	first := dial(t, srv, "?identity=alice")
+	dial(t, srv, "?identity=alice")
+	dial(t, srv, "?identity=bob")
+	waitForClients(t, hub, 3)
+	if err := hub.Admit("alice"); !errors.Is(err, ErrTooManyConnections) {
+		t.Fatalf("Admit(alice) = %v, want ErrTooManyConnections", err)
+	}
+
+	extra := dial(t, srv, "?identity=alice")
+	extra.SetReadDeadline(time.Now().Add(2 * time.Second))
+	_, _, err := extra.ReadMessage()
+	if !websocket.IsCloseError(err, CloseTooManyConnections) {
+		t.Fatalf("third connection: got %v, want close %d", err, CloseTooManyConnections)
+	}
+
+	// đóng một kết nối thì slot được trả lại
+	first.Close()
+	deadline := time.Now().Add(2 * time.Second)
+	for hub.Admit("alice") != nil {
+		if time.Now().After(deadline) {
+			t.Fatal("slot not released after disconnect")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestHubShutdownSendsGoingAway(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	srv := newTestServer(t, hub)
+
+	conns := []*websocket.Conn{dial(t, srv, ""), dial(t, srv, "?tenant=acme")}
+	stream := NewStreamClient(hub, nil, domain.Scope{}, "sse")
+	waitForClients(t, hub, 3)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := hub.Shutdown(ctx); err != nil {
+		t.Fatalf("shutdown: %v", err)
+	}
+
+	for i, conn := range conns {
+		conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+		_, _, err := conn.ReadMessage()
+		if !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+			t.Fatalf("client %d: got %v, want close %d", i, err, websocket.CloseGoingAway)
+		}
+	}
+	if _, ok := <-stream.Events(); ok {
+		t.Fatal("stream client: channel still open after shutdown")
+	}
+	stream.Close() // không được block sau shutdown
+
+	// hub đã dừng: không block và đóng ngay client mới
+	hub.Broadcast(context.Background(), EventItemCreated, "late")
+	late := dial(t, srv, "")
+	late.SetReadDeadline(time.Now().Add(2 * time.Second))
+	if _, _, err := late.ReadMessage(); !websocket.IsCloseError(err, websocket.CloseGoingAway) {
+		t.Fatalf("late client: got %v, want close %d", err, websocket.CloseGoingAway)
+	}
+	if n := hub.ClientCount(); n != 0 {
+		t.Fatalf("ClientCount after shutdown = %d, want 0", n)
+	}
+}
+
+// BenchmarkHubFanOut đo thời gian từ Broadcast tới khi Run đã đưa event vào
+// hàng đợi của mọi client.
+func BenchmarkHubFanOut(b *testing.B) {
+	for _, clients := range []int{1_000, 10_000, 50_000} {
+		b.Run(fmt.Sprintf("clients=%d", clients), func(b *testing.B) {
+			benchmarkFanOut(b, clients)
+		})
+	}
+}
+
+func benchmarkFanOut(b *testing.B, clients int) {
+	out := log.Writer()
+	log.SetOutput(io.Discard)
+	defer log.SetOutput(out)
+
+	hub := NewHub(WithReplayBuffer(0), WithSlowConsumerPolicy(SlowConsumerDropOldest))
+	go hub.Run()
+	defer hub.Shutdown(context.Background())
+
+	for i := 0; i < clients; i++ {
+		c := NewStreamClient(hub, nil, domain.Scope{}, fmt.Sprintf("c%d", i))
+		go func() {
+			for range c.Events() {
+			}
+		}()
+	}
+	for hub.ClientCount() < clients {
+		time.Sleep(time.Millisecond)
+	}
+
+	item := &domain.Item{ID: "bench", Name: "bench"}
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		hub.Broadcast(context.Background(), EventItemUpdated, item)
+		for hub.processed.Load() < uint64(i+1) {
+			runtime.Gosched()
+		}
+	}
+	b.StopTimer()
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(clients), "ns/client")
+}
@@ -0,0 +1,249 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// OverflowPolicy quyết định Broadcast làm gì khi hàng đợi broadcast của hub
+// (256 event) đầy vì Run xử lý không kịp.
+type OverflowPolicy string
+
+const (
+	// OverflowBlock chờ có chỗ tối đa timeout của WithOverflowPolicy (và ctx).
+	OverflowBlock OverflowPolicy = "block"
+	// OverflowDrop bỏ event ngay, chỉ tăng BroadcastDropped.
+	OverflowDrop OverflowPolicy = "drop"
+	// OverflowSpill ghi event vào Spill (queue bền, xem WithSpill) rồi phát
+	// lại theo đúng thứ tự khi hàng đợi có chỗ.
+	OverflowSpill OverflowPolicy = "spill"
+)
+
+const (
+	defaultOverflowTimeout = time.Second
+	spillRetry             = time.Second
+	spillPoll              = 5 * time.Millisecond
+)
+
+var (
+	ErrHubClosed        = fmt.Errorf("%w: hub closed", domain.ErrUnavailable)
+	ErrBroadcastDropped = fmt.Errorf("%w: broadcast queue full, event dropped", domain.ErrUnavailable)
+	ErrBroadcastTimeout = fmt.Errorf("%w: broadcast queue full, timed out", domain.ErrUnavailable)
+)
+
+func ParseOverflowPolicy(s string) (OverflowPolicy, error) {
+	switch p := OverflowPolicy(s); p {
+	case OverflowBlock, OverflowDrop, OverflowSpill:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid overflow policy %q, want block, drop or spill", s)
+}
+
+// WithOverflowPolicy đặt policy khi hàng đợi broadcast đầy, mặc định block
+// tối đa 1s. timeout chỉ dùng cho OverflowBlock; <= 0 là chỉ chờ theo ctx.
+// Event nhận từ backplane cũng đi qua policy này.
+func WithOverflowPolicy(p OverflowPolicy, timeout time.Duration) Option {
+	return func(h *Hub) {
+		h.overflow = p
+		h.overflowTimeout = timeout
+	}
+}
+
+// Spill là queue bền giữ event khi hàng đợi broadcast đầy. Event là JSON
+// WSEvent (cùng dạng gửi qua backplane).
+type Spill interface {
+	Push(ctx context.Context, data []byte) error
+	// Pop lấy event cũ nhất; ok = false khi queue rỗng.
+	Pop(ctx context.Context) (data []byte, ok bool, err error)
+}
+
+// WithSpill đặt queue cho OverflowSpill. Event còn lại từ lần chạy trước
+// được phát trước mọi event mới.
+func WithSpill(s Spill) Option {
+	return func(h *Hub) { h.spill = s }
+}
+
+// BroadcastDropped trả về số event Broadcast đã bỏ (drop, hết thời gian chờ
+// hoặc không ghi được vào spill).
+func (h *Hub) BroadcastDropped() uint64 {
+	return h.broadcastDropped.Load()
+}
+
+// enqueue đưa event vào hàng đợi của Run theo overflow policy.
+func (h *Hub) enqueue(ctx context.Context, event WSEvent) error {
+	select {
+	case <-h.done:
+		return ErrHubClosed
+	default:
+	}
+	if h.overflow == OverflowSpill {
+		return h.enqueueSpill(ctx, event)
+	}
+
+	select {
+	case h.broadcast <- event:
+		return nil
+	default:
+	}
+
+	if h.overflow == OverflowDrop {
+		h.broadcastDropped.Add(1)
+		return ErrBroadcastDropped
+	}
+
+	if h.overflowTimeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, h.overflowTimeout)
+		defer cancel()
+	}
+	select {
+	case h.broadcast <- event:
+		return nil
+	case <-h.done:
+		return ErrHubClosed
+	case <-ctx.Done():
+		h.broadcastDropped.Add(1)
+		return fmt.Errorf("%w: %v", ErrBroadcastTimeout, ctx.Err())
+	}
+}
+
+// spillState: khi spilling, mọi event mới đều vào spill (kể cả khi hàng đợi
+// đã có chỗ) để không vượt lên trước event đang nằm trong spill.
+type spillState struct {
+	mu       sync.Mutex
+	spilling bool
+	wake     chan struct{}
+}
+
+func (h *Hub) enqueueSpill(ctx context.Context, event WSEvent) error {
+	h.spillState.mu.Lock()
+	defer h.spillState.mu.Unlock()
+
+	if !h.spillState.spilling {
+		select {
+		case h.broadcast <- event:
+			return nil
+		default:
+		}
+		h.spillState.spilling = true
+		log.Printf("[WS] Broadcast queue full, spilling events")
+	}
+
+	data, err := json.Marshal(event)
+	if err == nil {
+		err = h.spill.Push(ctx, data)
+	}
+	if err != nil {
+		h.broadcastDropped.Add(1)
+		return fmt.Errorf("%w: spill: %v", ErrBroadcastDropped, err)
+	}
+	select {
+	case h.spillState.wake <- struct{}{}:
+	default:
+	}
+	return nil
+}
+
+// drainSpill chạy cùng Run, phát lại event trong spill theo thứ tự. Khi
+// spilling chỉ goroutine này gửi vào h.broadcast nên thấy còn chỗ thì lần
+// gửi sau chắc chắn không block; event chỉ được Pop khi đã có chỗ nên dừng
+// giữa chừng không làm mất event.
+func (h *Hub) drainSpill() {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		<-h.quit
+		cancel()
+	}()
+
+	for {
+		for len(h.broadcast) == cap(h.broadcast) {
+			if !sleepOrQuit(h.quit, spillPoll) {
+				return
+			}
+		}
+
+		h.spillState.mu.Lock()
+		data, ok, err := h.spill.Pop(ctx)
+		if err == nil && !ok {
+			h.spillState.spilling = false
+		}
+		h.spillState.mu.Unlock()
+
+		switch {
+		case err != nil:
+			if ctx.Err() != nil {
+				return
+			}
+			log.Printf("[WS] Spill pop error: %v", err)
+			if !sleepOrQuit(h.quit, spillRetry) {
+				return
+			}
+		case !ok:
+			select {
+			case <-h.spillState.wake:
+			case <-h.quit:
+				return
+			}
+		default:
+			if event, ok := decodeRemote(data); ok {
+				select {
+				case h.broadcast <- event:
+				case <-h.quit:
+					return
+				}
+			}
+		}
+	}
+}
+
+func sleepOrQuit(quit <-chan struct{}, d time.Duration) bool {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return true
+	case <-quit:
+		return false
+	}
+}
+
+// MemorySpill là Spill trong bộ nhớ, không bền; dùng cho test và dev.
+type MemorySpill struct {
+	mu    sync.Mutex
+	queue [][]byte
+}
+
+func NewMemorySpill() *MemorySpill {
+	return &MemorySpill{}
+}
+
+func (s *MemorySpill) Push(ctx context.Context, data []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.queue = append(s.queue, data)
+	return nil
+}
+
+func (s *MemorySpill) Pop(ctx context.Context) ([]byte, bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.queue) == 0 {
+		return nil, false, nil
+	}
+	data := s.queue[0]
+	s.queue = s.queue[1:]
+	return data, true, nil
+}
+
+func (s *MemorySpill) Len() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.queue)
+}
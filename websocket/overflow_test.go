@@ -0,0 +1,120 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// fillQueue lấp đầy hàng đợi broadcast của hub chưa chạy Run.
+func fillQueue(t *testing.T, hub *Hub) {
+	t.Helper()
+	for i := 0; i < cap(hub.broadcast); i++ {
+		if err := hub.Broadcast(context.Background(), EventItemCreated, i); err != nil {
+			t.Fatalf("broadcast #%d: %v", i, err)
+		}
+	}
+}
+
+func TestBroadcastOverflowDrop(t *testing.T) {
+	hub := NewHub(WithOverflowPolicy(OverflowDrop, 0))
+	fillQueue(t, hub)
+
+	err := hub.Broadcast(context.Background(), EventItemCreated, "overflow")
+	if !errors.Is(err, ErrBroadcastDropped) || !errors.Is(err, domain.ErrUnavailable) {
+		t.Fatalf("got %v, want ErrBroadcastDropped", err)
+	}
+	if hub.BroadcastDropped() != 1 {
+		t.Fatalf("BroadcastDropped = %d, want 1", hub.BroadcastDropped())
+	}
+}
+
+func TestBroadcastOverflowBlock(t *testing.T) {
+	hub := NewHub(WithOverflowPolicy(OverflowBlock, 50*time.Millisecond))
+	fillQueue(t, hub)
+
+	start := time.Now()
+	err := hub.Broadcast(context.Background(), EventItemCreated, "overflow")
+	if !errors.Is(err, ErrBroadcastTimeout) {
+		t.Fatalf("got %v, want ErrBroadcastTimeout", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Fatalf("blocked for %s, want ~50ms", elapsed)
+	}
+
+	// ctx của caller ngắn hơn timeout của hub
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := hub.Broadcast(ctx, EventItemCreated, "overflow"); !errors.Is(err, context.DeadlineExceeded) && !errors.Is(err, ErrBroadcastTimeout) {
+		t.Fatalf("got %v, want timeout", err)
+	}
+	if hub.BroadcastDropped() != 2 {
+		t.Fatalf("BroadcastDropped = %d, want 2", hub.BroadcastDropped())
+	}
+
+	// có chỗ trở lại thì Broadcast đang chờ được nhận
+	done := make(chan error, 1)
+	go func() { done <- hub.Broadcast(context.Background(), EventItemCreated, "late") }()
+	<-hub.broadcast
+	if err := <-done; err != nil {
+		t.Fatalf("broadcast after room freed: %v", err)
+	}
+}
+
+func TestBroadcastOverflowSpillKeepsOrder(t *testing.T) {
+	spill := NewMemorySpill()
+	// event còn trong spill từ lần chạy trước phải được phát trước
+	spill.Push(context.Background(), []byte(`{"type":"ITEM_CREATED","payload":-1,"timestamp":"2024-01-01T00:00:00Z"}`))
+
+	hub := NewHub(WithOverflowPolicy(OverflowSpill, 0), WithSpill(spill), WithReplayBuffer(1000))
+	go hub.Run()
+
+	// giữ lock để Run kẹt ở fan-out, hàng đợi đầy và event tràn sang spill
+	waitForSeq(t, hub, 1)
+	hub.mu.Lock()
+	r := hub.rooms[""]
+	const n = 600
+	for i := 0; i < n; i++ {
+		if err := hub.Broadcast(context.Background(), EventItemCreated, i); err != nil {
+			hub.mu.Unlock()
+			t.Fatalf("broadcast #%d: %v", i, err)
+		}
+	}
+	spilled := spill.Len()
+	hub.mu.Unlock()
+	if spilled == 0 {
+		t.Fatal("expected events to spill while the hub was stalled")
+	}
+
+	waitForSeq(t, hub, n+1)
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	for i, ev := range r.history {
+		var got struct{ Payload int }
+		if err := json.Unmarshal(ev.data, &got); err != nil {
+			t.Fatal(err)
+		}
+		if got.Payload != i-1 {
+			t.Fatalf("event #%d has payload %d, want %d (order broken)", ev.seq, got.Payload, i-1)
+		}
+	}
+	if hub.BroadcastDropped() != 0 {
+		t.Fatalf("BroadcastDropped = %d, want 0", hub.BroadcastDropped())
+	}
+}
+
+func TestBroadcastAfterShutdown(t *testing.T) {
+	hub := NewHub()
+	go hub.Run()
+	if err := hub.Shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+	if err := hub.Broadcast(context.Background(), EventItemCreated, "late"); !errors.Is(err, ErrHubClosed) {
+		t.Fatalf("got %v, want ErrHubClosed", err)
+	}
+}
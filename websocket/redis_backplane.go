@@ -0,0 +1,58 @@
+package websocket
+
+import (
+	"context"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisBackplane dùng Redis pub/sub. go-redis tự reconnect PubSub khi mất
+// kết nối; event publish trong lúc mất kết nối sẽ bị mất (at-most-once).
+type RedisBackplane struct {
+	client  redis.UniversalClient
+	channel string
+}
+
+func NewRedisBackplane(client redis.UniversalClient, channel string) *RedisBackplane {
+	return &RedisBackplane{client: client, channel: channel}
+}
+
+func (b *RedisBackplane) Publish(ctx context.Context, data []byte) error {
+	return b.client.Publish(ctx, b.channel, data).Err()
+}
+
+func (b *RedisBackplane) Subscribe(ctx context.Context) (<-chan []byte, error) {
+	ps := b.client.Subscribe(ctx, b.channel)
+	// chờ xác nhận SUBSCRIBE để không lỡ event publish ngay sau khi trả về
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+
+	out := make(chan []byte, 256)
+	go func() {
+		defer close(out)
+		defer ps.Close()
+		msgs := ps.Channel()
+		for {
+			select {
+			case msg, ok := <-msgs:
+				if !ok {
+					return
+				}
+				select {
+				case out <- []byte(msg.Payload):
+				case <-ctx.Done():
+					return
+				}
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	return out, nil
+}
+
+func (b *RedisBackplane) Close() error {
+	return b.client.Close()
+}
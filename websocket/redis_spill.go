@@ -0,0 +1,34 @@
+package websocket
+
+import (
+	"context"
+	"errors"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// RedisSpill là Spill trên một Redis list (RPUSH/LPOP); bền theo cấu hình
+// persistence của Redis (AOF/RDB) và sống qua restart của server.
+type RedisSpill struct {
+	client redis.UniversalClient
+	key    string
+}
+
+func NewRedisSpill(client redis.UniversalClient, key string) *RedisSpill {
+	return &RedisSpill{client: client, key: key}
+}
+
+func (s *RedisSpill) Push(ctx context.Context, data []byte) error {
+	return s.client.RPush(ctx, s.key, data).Err()
+}
+
+func (s *RedisSpill) Pop(ctx context.Context) ([]byte, bool, error) {
+	data, err := s.client.LPop(ctx, s.key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, false, nil
+	}
+	if err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
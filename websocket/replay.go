@@ -0,0 +1,89 @@
+package websocket
+
+import (
+	"encoding/json"
+	"log"
+	"time"
+)
+
+type replayEvent struct {
+	seq  uint64
+	data []byte
+	meta eventMeta
+}
+
+// record gán seq (theo room) cho event, marshal và lưu vào replay buffer của room.
+func (h *Hub) record(r *room, event WSEvent, meta eventMeta) ([]byte, bool) {
+	event.Seq = r.seq.Load() + 1
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[WS] Marshal error: %v", err)
+		return nil, false
+	}
+	r.seq.Store(event.Seq)
+
+	if h.replaySize > 0 {
+		r.history = append(r.history, replayEvent{seq: event.Seq, data: data, meta: meta})
+		if len(r.history) > h.replaySize {
+			r.history = r.history[len(r.history)-h.replaySize:]
+		}
+	}
+	return data, true
+}
+
+// replayTo gửi lại các event có seq > since mà client đang subscribe. Nếu
+// buffer không còn đủ event (hoặc không vừa send buffer của client) thì gửi
+// RESYNC_REQUIRED thay thế. Chạy trong goroutine Run.
+func (h *Hub) replayTo(r *room, c *Client, since uint64) {
+	last := r.seq.Load()
+	if since == last {
+		return
+	}
+	if since > last || len(r.history) == 0 || r.history[0].seq > since+1 {
+		h.sendResync(c, since, last)
+		return
+	}
+
+	missed := r.history[len(r.history)-int(last-since):]
+	var pending [][]byte
+	for _, ev := range missed {
+		if c.matches(ev.meta) {
+			pending = append(pending, ev.data)
+		}
+	}
+	if len(pending) > cap(c.send)-len(c.send) {
+		h.sendResync(c, since, last)
+		return
+	}
+
+	for _, data := range pending {
+		select {
+		case c.send <- data:
+		default:
+			h.sendResync(c, since, last)
+			return
+		}
+	}
+}
+
+func (h *Hub) sendResync(c *Client, since, last uint64) {
+	h.sendDirect(c, WSEvent{
+		Seq:       last,
+		Type:      EventResyncRequired,
+		Payload:   map[string]uint64{"since": since, "latest": last},
+		Timestamp: time.Now(),
+	})
+}
+
+// sendDirect gửi event riêng cho một client, không qua seq/replay buffer.
+func (h *Hub) sendDirect(c *Client, event WSEvent) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		log.Printf("[WS] Marshal error: %v", err)
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
+	}
+}
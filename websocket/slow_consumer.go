@@ -0,0 +1,132 @@
+package websocket
+
+import (
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+// SlowConsumerPolicy quyết định hub làm gì khi hàng đợi gửi của một client
+// đã đầy (client đọc chậm hơn tốc độ event).
+type SlowConsumerPolicy string
+
+const (
+	// SlowConsumerDisconnect ngắt client với close code CloseSlowConsumer;
+	// client reconnect với ?since= để replay phần đã lỡ.
+	SlowConsumerDisconnect SlowConsumerPolicy = "disconnect"
+	// SlowConsumerDropOldest bỏ event cũ nhất trong hàng đợi để nhận event
+	// mới; client thấy seq bị nhảy và có thể gửi resume.
+	SlowConsumerDropOldest SlowConsumerPolicy = "drop-oldest"
+	// SlowConsumerResync bỏ cả hàng đợi, thay bằng một RESYNC_REQUIRED để
+	// client tải lại list qua REST rồi nhận tiếp event mới.
+	SlowConsumerResync SlowConsumerPolicy = "resync"
+)
+
+// CloseSlowConsumer là close code khi client bị ngắt vì đọc không kịp.
+const CloseSlowConsumer = 4408
+
+func ParseSlowConsumerPolicy(s string) (SlowConsumerPolicy, error) {
+	switch p := SlowConsumerPolicy(s); p {
+	case SlowConsumerDisconnect, SlowConsumerDropOldest, SlowConsumerResync:
+		return p, nil
+	}
+	return "", fmt.Errorf("invalid slow consumer policy %q, want disconnect, drop-oldest or resync", s)
+}
+
+// WithSlowConsumerPolicy đặt policy cho client đọc chậm, mặc định disconnect.
+func WithSlowConsumerPolicy(p SlowConsumerPolicy) Option {
+	return func(h *Hub) { h.slowPolicy = p }
+}
+
+// ClientStats là thống kê của một client đang kết nối.
+type ClientStats struct {
+	ID       string `json:"id"`
+	Identity string `json:"identity"`
+	TenantID string `json:"tenant_id,omitempty"`
+	// Queued là số message đang chờ gửi.
+	Queued int `json:"queued"`
+	// Dropped là tổng số event client không nhận được do đọc chậm.
+	Dropped uint64 `json:"dropped"`
+}
+
+// ClientStats trả về thống kê của mọi client đang kết nối.
+func (h *Hub) ClientStats() []ClientStats {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	var out []ClientStats
+	for tenantID, r := range h.rooms {
+		for c := range r.clients {
+			out = append(out, ClientStats{
+				ID:       c.id,
+				Identity: c.identity,
+				TenantID: tenantID,
+				Queued:   len(c.send),
+				Dropped:  c.dropped.Load(),
+			})
+		}
+	}
+	return out
+}
+
+// Dropped trả về tổng số event bị bỏ do client chậm, kể cả client đã ngắt.
+func (h *Hub) Dropped() uint64 {
+	return h.dropped.Load()
+}
+
+// deliver đưa message vào hàng đợi của client, hàng đợi đầy thì áp dụng
+// slowPolicy. Chỉ goroutine Run gửi vào c.send nên sau khi lấy bớt message
+// ra, lần gửi kế tiếp chắc chắn có chỗ. Trả về false khi client phải bị ngắt.
+func (h *Hub) deliver(c *Client, message []byte, seq uint64) bool {
+	select {
+	case c.send <- message:
+		c.lagging = false
+		return true
+	default:
+	}
+
+	switch h.slowPolicy {
+	case SlowConsumerDropOldest:
+		select {
+		case <-c.send:
+		default:
+		}
+		c.send <- message
+		h.reportDrop(c, 1)
+
+	case SlowConsumerResync:
+		n := uint64(1) // event hiện tại cũng được thay bằng RESYNC_REQUIRED
+		for drained := false; !drained; {
+			select {
+			case <-c.send:
+				n++
+			default:
+				drained = true
+			}
+		}
+		h.reportDrop(c, n)
+		h.sendDirect(c, WSEvent{
+			Seq:       seq,
+			Type:      EventResyncRequired,
+			Payload:   map[string]interface{}{"latest": seq, "reason": "slow_consumer"},
+			Timestamp: time.Now(),
+		})
+
+	default:
+		h.reportDrop(c, 1)
+		c.closeFrame = websocket.FormatCloseMessage(CloseSlowConsumer, "slow consumer")
+		return false
+	}
+	return true
+}
+
+// reportDrop cộng số event bị bỏ và log một lần cho mỗi đợt client bị chậm.
+func (h *Hub) reportDrop(c *Client, n uint64) {
+	total := c.dropped.Add(n)
+	h.dropped.Add(n)
+	if !c.lagging {
+		c.lagging = true
+		log.Printf("[WS] Slow client %s (%s): policy %s, %d event(s) dropped so far", c.id, c.identity, h.slowPolicy, total)
+	}
+}
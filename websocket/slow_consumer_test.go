@@ -0,0 +1,122 @@
+package websocket
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"testing"
+
+	"github.com/gorilla/websocket"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// fillSlowClient đăng ký một stream client không đọc gì rồi phát n event,
+// trong lúc một goroutine khác liên tục đọc thống kê của hub để -race bắt
+// được truy cập map client không có lock.
+func fillSlowClient(t *testing.T, policy SlowConsumerPolicy, n int) (*Hub, *Client) {
+	t.Helper()
+	hub := NewHub(WithSlowConsumerPolicy(policy), WithReplayBuffer(0))
+	go hub.Run()
+
+	slow := NewStreamClient(hub, nil, domain.Scope{}, "slow")
+	waitForClients(t, hub, 1)
+
+	stop := make(chan struct{})
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				hub.ClientCount()
+				hub.ClientStats()
+				hub.Admit("slow")
+			}
+		}
+	}()
+
+	for i := 0; i < n; i++ {
+		hub.Broadcast(context.Background(), EventItemCreated, i)
+	}
+	waitForSeq(t, hub, uint64(n))
+	waitForFanOut(t, hub, uint64(n))
+	close(stop)
+	wg.Wait()
+	return hub, slow
+}
+
+func queuedEvents(t *testing.T, c *Client) []WSEvent {
+	t.Helper()
+	var out []WSEvent
+	for len(c.send) > 0 {
+		var ev WSEvent
+		if err := json.Unmarshal(<-c.send, &ev); err != nil {
+			t.Fatal(err)
+		}
+		out = append(out, ev)
+	}
+	return out
+}
+
+func TestSlowConsumerDisconnect(t *testing.T) {
+	hub, slow := fillSlowClient(t, SlowConsumerDisconnect, 300)
+
+	got := 0
+	for range slow.Events() {
+		got++
+	}
+	if got != cap(slow.send) {
+		t.Fatalf("received %d queued events, want %d", got, cap(slow.send))
+	}
+	if code := closeCode(slow.closeFrame); code != CloseSlowConsumer {
+		t.Fatalf("close code = %d, want %d", code, CloseSlowConsumer)
+	}
+	if hub.ClientCount() != 0 || hub.Dropped() != 1 {
+		t.Fatalf("clients = %d, dropped = %d; want 0, 1", hub.ClientCount(), hub.Dropped())
+	}
+	slow.Close() // unregister sau khi bị ngắt không được đóng channel lần nữa
+}
+
+func TestSlowConsumerDropOldest(t *testing.T) {
+	hub, slow := fillSlowClient(t, SlowConsumerDropOldest, 300)
+
+	stats := hub.ClientStats()
+	if len(stats) != 1 || stats[0].Dropped != 44 || stats[0].Queued != 256 {
+		t.Fatalf("stats = %+v, want 1 client with 44 dropped, 256 queued", stats)
+	}
+	events := queuedEvents(t, slow)
+	if first, last := events[0].Seq, events[len(events)-1].Seq; first != 45 || last != 300 {
+		t.Fatalf("queue holds seq %d..%d, want 45..300", first, last)
+	}
+}
+
+func TestSlowConsumerResync(t *testing.T) {
+	hub, slow := fillSlowClient(t, SlowConsumerResync, 300)
+
+	events := queuedEvents(t, slow)
+	if ev := events[0]; ev.Type != EventResyncRequired || ev.Seq != 257 {
+		t.Fatalf("first queued = %s #%d, want %s #257", ev.Type, ev.Seq, EventResyncRequired)
+	}
+	for i, ev := range events[1:] {
+		if want := uint64(258 + i); ev.Seq != want || ev.Type != EventItemCreated {
+			t.Fatalf("queued[%d] = %s #%d, want %s #%d", i+1, ev.Type, ev.Seq, EventItemCreated, want)
+		}
+	}
+	if len(events) != 44 || hub.Dropped() != 257 {
+		t.Fatalf("queued = %d, dropped = %d; want 44, 257", len(events), hub.Dropped())
+	}
+	if hub.ClientCount() != 1 {
+		t.Fatal("resync policy must keep the client connected")
+	}
+}
+
+func closeCode(frame []byte) int {
+	if len(frame) < 2 {
+		return websocket.CloseNoStatusReceived
+	}
+	return int(frame[0])<<8 | int(frame[1])
+}
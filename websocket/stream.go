@@ -0,0 +1,20 @@
+package websocket
+
+import "github.com/JIeeiroSst/hub/domain"
+
+// NewStreamClient đăng ký client không có kết nối WebSocket (ví dụ SSE).
+// Event đã marshal (cùng JSON WSEvent) được đọc từ Events(); gọi Close khi
+// kết nối phía dưới đóng. since, scope và identity có ý nghĩa như trong NewClient.
+func NewStreamClient(hub *Hub, since *uint64, scope domain.Scope, identity string) *Client {
+	return NewClient(hub, nil, since, scope, identity)
+}
+
+// Events trả về channel event của client; channel bị đóng khi hub ngắt client.
+func (c *Client) Events() <-chan []byte {
+	return c.send
+}
+
+// Close huỷ đăng ký client khỏi hub.
+func (c *Client) Close() {
+	c.hub.leave(c)
+}
@@ -0,0 +1,160 @@
+package websocket
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/JIeeiroSst/hub/domain"
+)
+
+// defaultMaxSubscriptions: mỗi subscription được kiểm tra với mọi event của
+// room, không giới hạn thì một client có thể làm chậm cả hub.
+const defaultMaxSubscriptions = 100
+
+// clientMessage là frame client gửi lên, ví dụ:
+//
+//	{"type":"resume","since":42}
+//	{"type":"subscribe","id":"s1","item_id":"<uuid>"}
+//	{"type":"subscribe","event_type":"ITEM_CREATED","name":"report*"}
+//	{"type":"unsubscribe","id":"s1"}
+//
+// Các điều kiện trong một subscription được AND với nhau; client nhận event
+// nếu khớp bất kỳ subscription nào. Client chưa subscribe gì nhận mọi event.
+type clientMessage struct {
+	Type      string    `json:"type"`
+	Since     uint64    `json:"since,omitempty"`
+	ID        string    `json:"id,omitempty"`
+	ItemID    string    `json:"item_id,omitempty"`
+	EventType EventType `json:"event_type,omitempty"`
+	Name      string    `json:"name,omitempty"`
+}
+
+type clientRequest struct {
+	client *Client
+	msg    clientMessage
+}
+
+type subscription struct {
+	itemID    string
+	eventType EventType
+	name      *regexp.Regexp
+}
+
+// eventMeta là các field dùng để route event, tách từ payload một lần lúc record.
+type eventMeta struct {
+	eventType EventType
+	itemID    string
+	name      string
+	ownerID   string
+	tenantID  string
+}
+
+func newEventMeta(event WSEvent) eventMeta {
+	meta := eventMeta{eventType: event.Type}
+	switch p := event.Payload.(type) {
+	case *domain.Item:
+		meta.itemID, meta.name = p.ID, p.Name
+		meta.ownerID, meta.tenantID = p.OwnerID, p.TenantID
+	case map[string]string:
+		meta.itemID, meta.name = p["id"], p["name"]
+		meta.ownerID, meta.tenantID = p["owner_id"], p["tenant_id"]
+	case json.RawMessage:
+		// payload từ outbox, change feed hoặc backplane
+		var item struct {
+			ID       string `json:"id"`
+			Name     string `json:"name"`
+			OwnerID  string `json:"owner_id"`
+			TenantID string `json:"tenant_id"`
+		}
+		if json.Unmarshal(p, &item) == nil {
+			meta.itemID, meta.name = item.ID, item.Name
+			meta.ownerID, meta.tenantID = item.OwnerID, item.TenantID
+		}
+	}
+	return meta
+}
+
+// handleMessage chạy trong goroutine Run.
+func (h *Hub) handleMessage(r *room, c *Client, msg clientMessage) {
+	switch msg.Type {
+	case "resume":
+		h.replayTo(r, c, msg.Since)
+
+	case "subscribe":
+		id := subscriptionID(msg)
+		// subscribe lại cùng id chỉ thay điều kiện, không tính thêm vào giới hạn
+		if _, ok := c.subs[id]; !ok && h.maxSubs > 0 && len(c.subs) >= h.maxSubs {
+			h.sendDirect(c, WSEvent{Type: EventError, Payload: map[string]string{"error": fmt.Sprintf("too many subscriptions (max %d)", h.maxSubs)}, Timestamp: time.Now()})
+			return
+		}
+		c.subs[id] = newSubscription(msg)
+		h.sendDirect(c, WSEvent{Type: EventSubscribed, Payload: map[string]string{"id": id}, Timestamp: time.Now()})
+
+	case "unsubscribe":
+		id := subscriptionID(msg)
+		delete(c.subs, id)
+		h.sendDirect(c, WSEvent{Type: EventUnsubscribed, Payload: map[string]string{"id": id}, Timestamp: time.Now()})
+
+	default:
+		h.sendDirect(c, WSEvent{Type: EventError, Payload: map[string]string{"error": "unknown message type " + msg.Type}, Timestamp: time.Now()})
+	}
+}
+
+// subscriptionID: client không gửi id thì dùng chính bộ điều kiện làm id,
+// để unsubscribe với cùng điều kiện vẫn gỡ được.
+func subscriptionID(msg clientMessage) string {
+	if msg.ID != "" {
+		return msg.ID
+	}
+	return strings.Join([]string{msg.ItemID, string(msg.EventType), msg.Name}, "|")
+}
+
+func newSubscription(msg clientMessage) *subscription {
+	sub := &subscription{itemID: msg.ItemID, eventType: msg.EventType}
+	if msg.Name != "" {
+		sub.name = compileNameFilter(msg.Name)
+	}
+	return sub
+}
+
+// compileNameFilter: không có '*' thì match chuỗi con, có '*' thì là glob
+// trên toàn bộ Name; đều không phân biệt hoa thường.
+func compileNameFilter(expr string) *regexp.Regexp {
+	quoted := regexp.QuoteMeta(expr)
+	if !strings.Contains(expr, "*") {
+		return regexp.MustCompile("(?i)" + quoted)
+	}
+	return regexp.MustCompile("(?i)^" + strings.ReplaceAll(quoted, `\*`, ".*") + "$")
+}
+
+func (s *subscription) matches(meta eventMeta) bool {
+	if s.itemID != "" && s.itemID != meta.itemID {
+		return false
+	}
+	if s.eventType != "" && s.eventType != meta.eventType {
+		return false
+	}
+	if s.name != nil && !s.name.MatchString(meta.name) {
+		return false
+	}
+	return true
+}
+
+// matches kiểm tra quyền xem item trước, rồi mới tới subscription.
+func (c *Client) matches(meta eventMeta) bool {
+	if !c.scope.Allows(meta.ownerID, meta.tenantID) {
+		return false
+	}
+	if len(c.subs) == 0 {
+		return true
+	}
+	for _, sub := range c.subs {
+		if sub.matches(meta) {
+			return true
+		}
+	}
+	return false
+}